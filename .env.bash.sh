@@ -4,5 +4,6 @@ export DB_USERNAME=postgres
 export DB_HOST=127.0.0.1
 export DB_PASSWORD=
 export DB_NAME=bitcoin
+export BTC_NETWORK=mainnet
 export BTCD_DATADIR=/mnt/golang_bitcoin/.btcd/data/mainnet/blocks_ffldb
 export START_BLOCK=45234
@@ -2,8 +2,12 @@ package main
 
 import (
 	"encoding/json"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx"
 	"github.com/webdeveloppro/cryptopiggy/pkg/address"
@@ -41,8 +45,20 @@ func (a *App) Run(addr string) {
 // initializeRoutes - creates routers, runs automatically in Initialize
 func (a *App) initializeRoutes() {
 	a.Router.HandleFunc("/", a.mainPage).Methods("GET")
+	a.Router.HandleFunc("/difficulty", a.showDifficulty).Methods("GET")
+	a.Router.HandleFunc("/blocks", a.listBlocks).Methods("GET")
+	a.Router.HandleFunc("/pools", a.showPools).Methods("GET")
 	a.Router.HandleFunc("/{hash:[0-9a-zA-Z]+}", a.showBlock).Methods("GET")
-	a.Router.HandleFunc("/address/{hash:[0-9a-zA-Z]+}", a.showAddress).Methods("GET")
+	a.Router.HandleFunc("/block/height/{height:[0-9]+}", a.showBlockByHeight).Methods("GET")
+	a.Router.HandleFunc("/block/at/{timestamp}", a.showBlockByTime).Methods("GET")
+	a.Router.HandleFunc("/address/{hash:[0-9a-zA-Z-]+}", a.showAddress).Methods("GET")
+	a.Router.HandleFunc("/address/{hash:[0-9a-zA-Z-]+}/utxo", a.showAddressUTXO).Methods("GET")
+	a.Router.HandleFunc("/data/search", a.searchData).Methods("GET")
+	a.Router.HandleFunc("/tx/search", a.searchTransactions).Methods("GET")
+	a.Router.HandleFunc("/tx/decode", a.decodeTransaction).Methods("POST")
+	a.Router.HandleFunc("/tx/{hash:[0-9a-fA-F]{64}}", a.showTransaction).Methods("GET")
+	a.Router.HandleFunc("/tx/{hash:[0-9a-fA-F]{64}}/trace", a.traceTransaction).Methods("GET")
+	a.Router.HandleFunc("/tx/{hash:[0-9a-fA-F]{64}}/verify", a.verifyTransaction).Methods("GET")
 }
 
 func (a *App) mainPage(w http.ResponseWriter, r *http.Request) {
@@ -80,6 +96,45 @@ func (a *App) showBlock(w http.ResponseWriter, r *http.Request) {
 
 	storage := block.NewStorage(a.DB)
 	b, err := storage.GetByHash(vars["hash"])
+	a.respondWithBlock(w, b, err)
+}
+
+func (a *App) showBlockByHeight(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	height, err := strconv.ParseInt(vars["height"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Wrong block height")
+		return
+	}
+
+	storage := block.NewStorage(a.DB)
+	b, err := storage.GetByHeight(int32(height))
+	a.respondWithBlock(w, b, err)
+}
+
+func (a *App) showBlockByTime(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	t, err := parseTime(vars["timestamp"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Wrong timestamp")
+		return
+	}
+
+	mode, err := block.ParseTimeMode(r.URL.Query().Get("mode"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	storage := block.NewStorage(a.DB)
+	b, err := storage.GetByTime(t, mode)
+	a.respondWithBlock(w, b, err)
+}
+
+// respondWithBlock load block transactions and price and write block as json
+func (a *App) respondWithBlock(w http.ResponseWriter, b *block.Block, err error) {
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			respondWithError(w, http.StatusNotFound, "Block not found")
@@ -100,11 +155,118 @@ func (a *App) showBlock(w http.ResponseWriter, r *http.Request) {
 		// respondWithError(w, http.StatusServiceUnavailable, "Prices for block not found")
 		// return
 	}
+
+	if _, err := b.GetStats(); err != nil {
+		log.Printf("error in block get stats, %v", err)
+	}
+
+	if _, err := b.GetMiner(); err != nil {
+		log.Printf("error in block get miner, %v", err)
+	}
+
+	if _, err := b.GetReward(); err != nil {
+		log.Printf("error in block get reward, %v", err)
+	}
 	respondWithJSON(w, http.StatusOK, b)
 }
 
+func (a *App) listBlocks(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := block.Filter{}
+
+	var err error
+	if f.Before, err = parseHeight(q.Get("before")); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Wrong before height")
+		return
+	}
+
+	if f.After, err = parseHeight(q.Get("after")); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Wrong after height")
+		return
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Wrong limit")
+			return
+		}
+		f.Limit = limit
+	}
+
+	if f.From, err = parseTime(q.Get("from")); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Wrong from timestamp")
+		return
+	}
+
+	if f.To, err = parseTime(q.Get("to")); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Wrong to timestamp")
+		return
+	}
+
+	storage := block.NewStorage(a.DB)
+	page, err := block.List(&storage, f)
+	if err != nil {
+		if err == block.ErrBadCursor {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("error in block list, %v", err)
+		respondWithError(w, http.StatusServiceUnavailable, "Cannot retrieve blocks")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+func (a *App) showPools(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	from, err := parseTime(q.Get("from"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Wrong from timestamp")
+		return
+	}
+
+	to, err := parseTime(q.Get("to"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Wrong to timestamp")
+		return
+	}
+
+	storage := block.NewStorage(a.DB)
+	shares, err := block.PoolShares(&storage, q.Get("period"), from, to)
+	if err != nil {
+		if err == block.ErrBadPeriod {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("error in block pool shares, %v", err)
+		respondWithError(w, http.StatusServiceUnavailable, "Cannot retrieve pool shares")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, shares)
+}
+
+func (a *App) showDifficulty(w http.ResponseWriter, r *http.Request) {
+	storage := block.NewStorage(a.DB)
+	retargets, err := block.Retargets(&storage)
+	if err != nil {
+		log.Printf("error in block retargets, %v", err)
+		respondWithError(w, http.StatusServiceUnavailable, "Cannot retrieve difficulty history")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, retargets)
+}
+
 func (a *App) showAddress(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
+	if err := address.Validate(vars["hash"]); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	storage := address.NewStorage(a.DB)
 	addr := address.New(&storage)
@@ -135,6 +297,317 @@ func (a *App) showAddress(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, addr)
 }
 
+func (a *App) showAddressUTXO(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := address.Validate(vars["hash"]); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	storage := address.NewStorage(a.DB)
+	addr := address.New(&storage)
+	if err := addr.GetByHash(vars["hash"]); err != nil {
+		if err == pgx.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Address not found")
+		} else {
+			log.Printf("error during show address utxo, %v", err)
+			respondWithError(w, http.StatusBadRequest, "Cannot retrieve address")
+		}
+		return
+	}
+
+	utxo, err := addr.GetUTXO()
+	if err == address.ErrNoUTXOSet {
+		respondWithError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	} else if err != nil {
+		log.Printf("app: error in address getutxo, %v", err)
+		respondWithError(w, http.StatusServiceUnavailable, "Cannot get unspent outputs for address")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, utxo)
+}
+
+func (a *App) searchData(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Wrong limit")
+			return
+		}
+	}
+
+	matches, err := transaction.SearchData(transaction.NewStorage(a.DB), q.Get("q"), limit)
+	if err != nil {
+		if err == transaction.ErrEmptyQuery {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		} else {
+			log.Printf("app: error in data search, %v", err)
+			respondWithError(w, http.StatusServiceUnavailable, "Cannot search data")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, matches)
+}
+
+// parseInt64 return nil for empty string
+func parseInt64(v string) (*int64, error) {
+	if v == "" {
+		return nil, nil
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// parseInt return nil for empty string
+func parseInt(v string) (*int, error) {
+	if v == "" {
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// parseHeight return nil for empty string
+func parseHeight(v string) (*int32, error) {
+	if v == "" {
+		return nil, nil
+	}
+
+	height, err := strconv.ParseInt(v, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	h := int32(height)
+	return &h, nil
+}
+
+// parseTime accept unix timestamp, RFC3339 or YYYY-MM-DD date, empty string is zero time
+func parseTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	if unix, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(unix, 0).UTC(), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UTC(), nil
+	}
+
+	return time.Parse("2006-01-02", v)
+}
+
+func (a *App) showTransaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	t, err := transaction.GetTransactionDetail(transaction.NewStorage(a.DB), vars["hash"])
+	if err != nil {
+		if err == transaction.ErrNoTran {
+			respondWithError(w, http.StatusNotFound, "Transaction not found")
+			return
+		}
+		log.Printf("app: error in transaction detail, %v", err)
+		respondWithError(w, http.StatusServiceUnavailable, "Cannot retrieve transaction")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, t)
+}
+
+func (a *App) searchTransactions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := transaction.SearchFilter{
+		Address:    q.Get("address"),
+		ScriptType: q.Get("script_type"),
+		Sort:       q.Get("sort"),
+		Order:      q.Get("order"),
+	}
+
+	var err error
+	if f.FromHeight, err = parseHeight(q.Get("from_height")); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Wrong from_height")
+		return
+	}
+
+	if f.ToHeight, err = parseHeight(q.Get("to_height")); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Wrong to_height")
+		return
+	}
+
+	if f.From, err = parseTime(q.Get("from")); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Wrong from timestamp")
+		return
+	}
+
+	if f.To, err = parseTime(q.Get("to")); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Wrong to timestamp")
+		return
+	}
+
+	amounts := map[string]**int64{
+		"min_value": &f.MinValue,
+		"max_value": &f.MaxValue,
+		"min_fee":   &f.MinFee,
+		"max_fee":   &f.MaxFee,
+	}
+	for name, dst := range amounts {
+		if *dst, err = parseInt64(q.Get(name)); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Wrong "+name)
+			return
+		}
+	}
+
+	counts := map[string]**int{
+		"min_inputs":  &f.MinInputs,
+		"max_inputs":  &f.MaxInputs,
+		"min_outputs": &f.MinOutputs,
+		"max_outputs": &f.MaxOutputs,
+	}
+	for name, dst := range counts {
+		if *dst, err = parseInt(q.Get(name)); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Wrong "+name)
+			return
+		}
+	}
+
+	for name, dst := range map[string]*int{"limit": &f.Limit, "offset": &f.Offset} {
+		if v, err := parseInt(q.Get(name)); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Wrong "+name)
+			return
+		} else if v != nil {
+			*dst = *v
+		}
+	}
+
+	page, err := transaction.Search(transaction.NewStorage(a.DB), f)
+	if err != nil {
+		if err == transaction.ErrBadSort || err == transaction.ErrBadScriptType || err == transaction.ErrBadRange {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("app: error in transaction search, %v", err)
+		respondWithError(w, http.StatusServiceUnavailable, "Cannot search transactions")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+func (a *App) traceTransaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	q := r.URL.Query()
+
+	opts := transaction.TraceOptions{
+		Direction: q.Get("direction"),
+		Method:    q.Get("method"),
+	}
+
+	if v := q.Get("depth"); v != "" {
+		depth, err := strconv.Atoi(v)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Wrong depth")
+			return
+		}
+		opts.Depth = depth
+	}
+
+	g, err := transaction.Trace(transaction.NewStorage(a.DB), vars["hash"], opts)
+	if err != nil {
+		if err == transaction.ErrNoTran {
+			respondWithError(w, http.StatusNotFound, "Transaction not found")
+		} else if err == transaction.ErrBadDirection || err == transaction.ErrBadTaintMethod {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		} else {
+			log.Printf("app: error in transaction trace, %v", err)
+			respondWithError(w, http.StatusServiceUnavailable, "Cannot trace transaction")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, g)
+}
+
+func (a *App) verifyTransaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	storage := transaction.NewStorage(a.DB)
+
+	t, err := transaction.FindTransaction(storage, "hash", vars["hash"])
+	if err == nil {
+		err = storage.GetBlockInfo(&t)
+	}
+	if err != nil {
+		if err == transaction.ErrNoTran {
+			respondWithError(w, http.StatusNotFound, "Transaction not found")
+			return
+		}
+		log.Printf("app: error in transaction verify, %v", err)
+		respondWithError(w, http.StatusServiceUnavailable, "Cannot retrieve transaction")
+		return
+	}
+
+	v, err := transaction.Verify(storage, &t)
+	if err != nil {
+		log.Printf("app: error in transaction verify, %v", err)
+		respondWithError(w, http.StatusServiceUnavailable, "Cannot verify transaction")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, v)
+}
+
+// maxRawTxBody limit request body of raw transaction decoding
+const maxRawTxBody = 4 << 20
+
+// decodeTransaction accept {"hex": "..."} json or raw transaction hex as request body
+func (a *App) decodeTransaction(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRawTxBody))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Cannot read request body")
+		return
+	}
+
+	raw := strings.TrimSpace(string(body))
+	if strings.HasPrefix(raw, "{") {
+		req := struct {
+			Hex string `json:"hex"`
+		}{}
+		if err := json.Unmarshal(body, &req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Wrong json body")
+			return
+		}
+		raw = req.Hex
+	}
+
+	d, err := transaction.Decode(transaction.NewStorage(a.DB), raw)
+	if err != nil {
+		if err == transaction.ErrBadRawTx {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		} else {
+			log.Printf("app: error in transaction decode, %v", err)
+			respondWithError(w, http.StatusServiceUnavailable, "Cannot decode transaction")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, d)
+}
+
 func respondWithError(w http.ResponseWriter, code int, message string) {
 	respondWithJSON(w, code, map[string]string{"error": message})
 }
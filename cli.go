@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/webdeveloppro/cryptopiggy/pkg/block"
+)
+
+// heightArgs parse optional [from height] [to height] command arguments,
+// by default range covers whole chain
+func heightArgs(storage block.Storage, args []string) (int64, int64, error) {
+	tip, err := storage.Tip()
+	if err != nil {
+		return 0, 0, fmt.Errorf("cannot get chain tip, %v", err)
+	}
+
+	from, to := int64(0), int64(tip.Height)
+	if len(args) > 0 {
+		if from, err = strconv.ParseInt(args[0], 10, 32); err != nil {
+			return 0, 0, fmt.Errorf("wrong from height %s", args[0])
+		}
+	}
+	if len(args) > 1 {
+		if to, err = strconv.ParseInt(args[1], 10, 32); err != nil {
+			return 0, 0, fmt.Errorf("wrong to height %s", args[1])
+		}
+	}
+	return from, to, nil
+}
@@ -2,4 +2,16 @@
 
 http http://crypto-base.webdevelop.biz
 http http://crypto-base.webdevelop.biz/000000000000007aef9d6f9d5e4d4fb50bea5334de9f7b1d12e743da54cb8875
+http http://crypto-base.webdevelop.biz/block/height/154722
 http http://crypto-base.webdevelop.biz/address/1LPXQf1foebcfLzZxcpK3sG2TJ9ke1uLyQ
+http http://crypto-base.webdevelop.biz/difficulty
+http "http://crypto-base.webdevelop.biz/blocks?from=2014-03-01&to=2014-04-01&limit=50"
+http "http://crypto-base.webdevelop.biz/block/at/2014-01-01?mode=after"
+http "http://crypto-base.webdevelop.biz/pools?period=month&from=2014-01-01"
+http http://crypto-base.webdevelop.biz/tx/669c479e1eb2bfcc25cf1406c9b6b922cf7dea5691d5e85d4cbcc4b32464f93d
+http http://crypto-base.webdevelop.biz/address/1LPXQf1foebcfLzZxcpK3sG2TJ9ke1uLyQ/utxo
+http "http://crypto-base.webdevelop.biz/data/search?q=omni"
+http "http://crypto-base.webdevelop.biz/tx/669c479e1eb2bfcc25cf1406c9b6b922cf7dea5691d5e85d4cbcc4b32464f93d/trace?direction=forward&depth=5&method=fifo"
+http "http://crypto-base.webdevelop.biz/tx/search?address=1LPXQf1foebcfLzZxcpK3sG2TJ9ke1uLyQ&from=2014-01-01&to=2015-01-01&sort=value"
+http http://crypto-base.webdevelop.biz/tx/669c479e1eb2bfcc25cf1406c9b6b922cf7dea5691d5e85d4cbcc4b32464f93d/verify
+http POST http://crypto-base.webdevelop.biz/tx/decode hex=0100000001...
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/jackc/pgx"
+	"github.com/webdeveloppro/cryptopiggy/pkg/network"
 )
 
 var a App
@@ -14,7 +15,7 @@ var ws WsApp
 func main() {
 
 	if len(os.Args) < 2 {
-		log.Fatal("Please use webapp or wsapp parameter: ./bitcoin2sql <param>")
+		log.Fatal("Please use webapp, wsapp, verify, scripts, miners, rewards or nonstandard parameter: ./bitcoin2sql <param>")
 	}
 
 	t := os.Args[1]
@@ -39,33 +40,24 @@ func main() {
 		return
 	}
 
-	if t == "webapp" {
-		connPoolConfig := pgx.ConnPoolConfig{
-			ConnConfig: pgx.ConnConfig{
-				Host:     host,
-				User:     user,
-				Password: dbpassword,
-				Database: dbname,
-			},
-			MaxConnections: 100,
-		}
+	// mainnet by default, testnet, signet, regtest or simnet for test chains
+	if err := network.Set(os.Getenv("BTC_NETWORK")); err != nil {
+		log.Fatalf("Wrong BTC_NETWORK env %q, %v", os.Getenv("BTC_NETWORK"), err)
+	}
+	log.Printf("Using %s network", network.Params().Name)
 
-		pool, err := pgx.NewConnPool(connPoolConfig)
-		if err != nil {
-			log.Fatalf("Unable to create connection pool %v", err)
-		}
+	connConfig := pgx.ConnConfig{
+		Host:     host,
+		User:     user,
+		Password: dbpassword,
+		Database: dbname,
+	}
 
-		a.Initialize(pool)
+	if t == "webapp" {
+		a.Initialize(newConnPool(connConfig))
 		a.Run("")
 	} else if t == "wsapp" {
-		conn, err := pgx.Connect(
-			pgx.ConnConfig{
-				Host:     host,
-				User:     user,
-				Password: dbpassword,
-				Database: dbname,
-			},
-		)
+		conn, err := pgx.Connect(connConfig)
 
 		if err != nil {
 			log.Fatalf("Unable to create connection %v", err)
@@ -74,7 +66,50 @@ func main() {
 		ws.Initialize(conn)
 		ws.Run("")
 		log.Fatal(http.ListenAndServe(":8082", nil))
+	} else if t == "verify" {
+		if err := verifyBlocks(newConnPool(connConfig), os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	} else if t == "scripts" {
+		if err := verifyScripts(newConnPool(connConfig), os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	} else if t == "miners" {
+		if err := attributeBlocks(newConnPool(connConfig), os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	} else if t == "rewards" {
+		if err := checkRewards(newConnPool(connConfig), os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	} else if t == "stats" {
+		if err := calcStats(newConnPool(connConfig), os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	} else if t == "nonstandard" {
+		if err := rekeyNonstandard(newConnPool(connConfig)); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	log.Fatal("Please use one of the options: webapp, wsapp, verify, scripts, miners, rewards, stats, nonstandard")
+}
+
+func newConnPool(connConfig pgx.ConnConfig) *pgx.ConnPool {
+	connPoolConfig := pgx.ConnPoolConfig{
+		ConnConfig:     connConfig,
+		MaxConnections: 100,
 	}
 
-	log.Fatal("Please use one of the options: webapp, wsapp")
+	pool, err := pgx.NewConnPool(connPoolConfig)
+	if err != nil {
+		log.Fatalf("Unable to create connection pool %v", err)
+	}
+	return pool
 }
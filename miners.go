@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx"
+	"github.com/webdeveloppro/cryptopiggy/pkg/block"
+)
+
+// attributeBlocks find mining pool for blocks in height range,
+// existing attribution is replaced, so it can be run after pool table changes
+// usage: ./bitcoin2sql miners [from height] [to height]
+func attributeBlocks(pool *pgx.ConnPool, args []string) error {
+	storage := block.NewStorage(pool)
+
+	from, to, err := heightArgs(&storage, args)
+	if err != nil {
+		return fmt.Errorf("miners: %v", err)
+	}
+
+	for height := from; height <= to; height++ {
+		b, err := storage.GetByHeight(int32(height))
+		if err == pgx.ErrNoRows {
+			log.Printf("miners: block %d not found", height)
+			continue
+		} else if err != nil {
+			return fmt.Errorf("miners: cannot get block %d, %v", height, err)
+		}
+
+		b.Miner = nil
+		m, err := b.GetMiner()
+		if err != nil {
+			log.Printf("miners: block %d, %v", height, err)
+			continue
+		}
+
+		if err := storage.SaveMiner(b.ID, m); err != nil {
+			return fmt.Errorf("miners: block %d, %v", height, err)
+		}
+	}
+
+	log.Printf("miners: attributed blocks %d-%d", from, to)
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx"
+	"github.com/webdeveloppro/cryptopiggy/pkg/block"
+)
+
+// rekeyNonstandard migrate addresses made by old nonstandard-<script prefix> scheme
+// to script based addresses, every address is moved in its own database transaction
+// so migration can be stopped and run again
+// usage: ./bitcoin2sql nonstandard
+func rekeyNonstandard(pool *pgx.ConnPool) error {
+	storage := block.NewStorage(pool)
+
+	ids, err := storage.NonstandardAddresses()
+	if err != nil {
+		return fmt.Errorf("nonstandard: %v", err)
+	}
+
+	total := 0
+	for _, id := range ids {
+		n, err := storage.RekeyAddress(id)
+		if err != nil {
+			return fmt.Errorf("nonstandard: cannot rekey address %d, %v", id, err)
+		}
+		total += n
+	}
+
+	log.Printf("nonstandard: migrated %d addresses, %d transactions", len(ids), total)
+	return nil
+}
@@ -1,12 +1,23 @@
 package address
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/btcsuite/btcutil"
 	"github.com/pkg/errors"
+	"github.com/webdeveloppro/cryptopiggy/pkg/network"
 	"github.com/webdeveloppro/cryptopiggy/pkg/transaction"
 )
 
+// ErrNoUTXOSet error for address with ballance but without unspent outputs,
+// utxo table is filled only for blocks inserted after sql/10_utxo.sql
+var ErrNoUTXOSet = fmt.Errorf("Utxo set not built for this address")
+
+// ErrBadAddress error for address which is not valid for selected network
+var ErrBadAddress = fmt.Errorf("Address is not valid for selected network")
+
 // Address Holds block data and table ID
 type Address struct {
 	ID           uint                      `json:"id" default:""`
@@ -19,6 +30,16 @@ type Address struct {
 	storage      Storage
 }
 
+// UTXO is unspent output which belongs to address
+type UTXO struct {
+	Hash     string  `json:"txid"`
+	Vout     uint32  `json:"vout"`
+	Value    int64   `json:"value"`
+	Height   int32   `json:"height"`
+	Price    float32 `json:"price"`     // btc price on the moment output was created
+	ValueUSD float64 `json:"value_usd"` // value in USD on the moment output was created
+}
+
 // New constructor for address structure
 func New(storage Storage) *Address {
 	a := Address{
@@ -35,6 +56,25 @@ func (a *Address) Save() error {
 	return a.storage.Update(a)
 }
 
+// Validate check address hash against selected network parameters,
+// nonstandard identifiers are accepted for any network
+func Validate(hash string) error {
+	if strings.HasPrefix(hash, transaction.NonstandardPrefix) {
+		return nil
+	}
+
+	params := network.Params()
+	if transaction.IsTaprootAddress(hash, params.Bech32HRPSegwit) {
+		return nil
+	}
+
+	addr, err := btcutil.DecodeAddress(hash, params)
+	if err != nil || !addr.IsForNet(params) || addr.EncodeAddress() != hash {
+		return ErrBadAddress
+	}
+	return nil
+}
+
 // GetByHash Find Address by Hash
 func (a *Address) GetByHash(hash string) error {
 	a.Hash = hash
@@ -58,6 +98,28 @@ func (a *Address) GetTransactions() error {
 	return nil
 }
 
+// GetUTXO return unspent outputs of address with their USD value at creation,
+// ErrNoUTXOSet is returned when address has ballance but no outputs were stored
+func (a *Address) GetUTXO() ([]UTXO, error) {
+	if a.ID == 0 {
+		return make([]UTXO, 0), nil
+	}
+
+	utxo, err := a.storage.GetUTXO(a.ID)
+	if err != nil {
+		return utxo, errors.Wrap(err, "address: cannot get utxo")
+	}
+
+	if len(utxo) == 0 && a.Ballance > 0 {
+		return utxo, ErrNoUTXOSet
+	}
+
+	for i := range utxo {
+		utxo[i].ValueUSD = float64(utxo[i].Value) / 1e8 * float64(utxo[i].Price)
+	}
+	return utxo, nil
+}
+
 // Last10 show last 10 address
 func Last10(storage Storage, order string) ([]*Address, error) {
 
@@ -35,6 +35,13 @@ func (f *FakeStorage) GetTransactions(index uint) ([]transaction.Transaction, er
 	return make([]transaction.Transaction, 0), nil
 }
 
+func (f *FakeStorage) GetUTXO(id uint) ([]address.UTXO, error) {
+	if id == 2 {
+		return []address.UTXO{}, nil
+	}
+	return []address.UTXO{{Hash: "tx", Vout: 1, Value: 50000000, Height: 100, Price: 200}}, nil
+}
+
 func (f *FakeStorage) GetAddresses(sql string, args ...interface{}) ([]*address.Address, error) {
 	return make([]*address.Address, 0), nil
 }
@@ -95,3 +102,61 @@ func TestMostRich10(t *testing.T) {
 		t.Errorf("Got error but should not")
 	}
 }
+
+func TestGetUTXO(t *testing.T) {
+	addr := address.New(&FakeStorage{})
+	utxo, err := addr.GetUTXO()
+	if err != nil || len(utxo) != 0 {
+		t.Errorf("GetUTXO should return empty list for unsaved address, got: %v, %v", utxo, err)
+	}
+
+	addr.ID = 1
+	utxo, err = addr.GetUTXO()
+	if err != nil || len(utxo) != 1 {
+		t.Fatalf("GetUTXO return wrong outputs, %v, %v", utxo, err)
+	}
+
+	if utxo[0].ValueUSD != 100 {
+		t.Errorf("GetUTXO return wrong usd value should 100, got: %f", utxo[0].ValueUSD)
+	}
+
+	// address imported before utxo set was built
+	addr.ID, addr.Ballance = 2, 5000
+	if _, err = addr.GetUTXO(); err != address.ErrNoUTXOSet {
+		t.Errorf("GetUTXO should return ErrNoUTXOSet for address with ballance, got: %v", err)
+	}
+
+	addr.Ballance = 0
+	if utxo, err = addr.GetUTXO(); err != nil || len(utxo) != 0 {
+		t.Errorf("GetUTXO should return empty list for empty address, got: %v, %v", utxo, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []string{
+		"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa",
+		"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy",
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0",
+		transaction.NonstandardPrefix + "76a9",
+	}
+	for _, hash := range valid {
+		if err := address.Validate(hash); err != nil {
+			t.Errorf("Validate return error for %s, %v", hash, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"badhash",
+		"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNb",
+		"mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn",
+		"tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx",
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj1",
+	}
+	for _, hash := range invalid {
+		if err := address.Validate(hash); err != address.ErrBadAddress {
+			t.Errorf("Validate should return ErrBadAddress for %s, got %v", hash, err)
+		}
+	}
+}
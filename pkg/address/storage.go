@@ -2,6 +2,7 @@ package address
 
 import (
 	"github.com/jackc/pgx"
+	"github.com/pkg/errors"
 	"github.com/webdeveloppro/cryptopiggy/pkg/transaction"
 )
 
@@ -11,6 +12,7 @@ type Storage interface {
 	Insert(*Address) error
 	Update(*Address) error
 	GetTransactions(uint) ([]transaction.Transaction, error)
+	GetUTXO(uint) ([]UTXO, error)
 	GetAddresses(string, ...interface{}) ([]*Address, error)
 }
 
@@ -78,6 +80,31 @@ func (pg *PGStorage) GetTransactions(id uint) ([]transaction.Transaction, error)
 	return transaction.FindTransactions(tranStorage, "address_hash", id)
 }
 
+// GetUTXO return unspent outputs for address id ordered by height
+func (pg *PGStorage) GetUTXO(id uint) ([]UTXO, error) {
+	utxo := make([]UTXO, 0)
+
+	rows, err := pg.con.Query(`
+		SELECT hash, vout, value, height, price
+		FROM utxo
+		WHERE address_id = $1
+		ORDER BY height, hash, vout`, id,
+	)
+	if err != nil {
+		return utxo, errors.Wrapf(err, "address: cannot select utxo for %d", id)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		u := UTXO{}
+		if err := rows.Scan(&u.Hash, &u.Vout, &u.Value, &u.Height, &u.Price); err != nil {
+			return utxo, errors.Wrap(err, "address: cannot retrieve utxo")
+		}
+		utxo = append(utxo, u)
+	}
+	return utxo, rows.Err()
+}
+
 // GetAddresses return address according to sql query
 func (pg *PGStorage) GetAddresses(sql string, args ...interface{}) ([]*Address, error) {
 	sql = "SELECT id, updated_at, hash, income, outcome, ballance FROM address " + sql
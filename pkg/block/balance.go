@@ -0,0 +1,70 @@
+package block
+
+import (
+	"sort"
+)
+
+// addressChange holds amounts block adds to address income and outcome
+type addressChange struct {
+	addressID uint
+	income    int64
+	outcome   int64
+}
+
+// balanceChanges return address_log rows for block transactions and
+// income/outcome changes per address sorted by address id.
+// address_log keep positive amount for income and negative for outcome
+func balanceChanges(b *Block) ([][]interface{}, []addressChange) {
+	rows := make([][]interface{}, 0)
+
+	for _, t := range b.Transactions {
+		for _, in := range t.TxIns {
+			if in.AddressID == 0 || in.Amount == 0 {
+				continue
+			}
+			rows = append(rows, []interface{}{in.AddressID, -in.Amount, b.CreatedAt, t.ID})
+		}
+
+		for _, out := range t.TxOuts {
+			if out.AddressID == 0 {
+				continue
+			}
+			rows = append(rows, []interface{}{out.AddressID, out.Value, b.CreatedAt, t.ID})
+		}
+	}
+
+	return rows, logChanges(rows)
+}
+
+// logChanges sum address_log rows to income/outcome changes sorted by address id
+func logChanges(rows [][]interface{}) []addressChange {
+	changes := make(map[uint]*addressChange)
+
+	for _, row := range rows {
+		id, amount := row[0].(uint), row[1].(int64)
+
+		c, ok := changes[id]
+		if !ok {
+			c = &addressChange{addressID: id}
+			changes[id] = c
+		}
+
+		if amount < 0 {
+			c.outcome -= amount
+		} else {
+			c.income += amount
+		}
+	}
+
+	sorted := make([]addressChange, 0, len(changes))
+	for _, c := range changes {
+		sorted = append(sorted, *c)
+	}
+
+	// same order for every insert to avoid deadlocks between parallel inserts
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].addressID < sorted[j].addressID
+	})
+
+	return sorted
+}
@@ -12,6 +12,7 @@ import (
 type Block struct {
 	ID             uint                      `json:"id" default:""`
 	Bits           uint32                    `json:"bits"`
+	Difficulty     float64                   `json:"difficulty"`
 	Height         int32                     `json:"height"`
 	Nonce          uint32                    `json:"nonce"`
 	Version        int32                     `json:"version"`
@@ -21,9 +22,20 @@ type Block struct {
 	Hash           string                    `json:"hash"`
 	Transactions   []transaction.Transaction `json:"transactions"`
 	Price          float32                   `json:"price"`
+	PrevBlock      *Ref                      `json:"prev_block"`
+	NextBlock      *Ref                      `json:"next_block"`
+	Stats          *BlockStats               `json:"stats"`
+	Miner          *Miner                    `json:"miner"`
+	Reward         *Reward                   `json:"reward"`
 	storage        Storage
 }
 
+// Ref points to a neighbour block in the chain
+type Ref struct {
+	Height int32  `json:"height"`
+	Hash   string `json:"hash"`
+}
+
 // New constructor for block structure
 func New(storage Storage) *Block {
 	b := Block{
@@ -59,9 +71,63 @@ func (b *Block) GetPrice() (err error) {
 	return err
 }
 
-// Insert will create new record for current block
+// GetStats return block statistics, if block do not have them yet
+// statistics are calculated from block transactions, use stats command to store them
+func (b *Block) GetStats() (*BlockStats, error) {
+	if b.Stats != nil {
+		return b.Stats, nil
+	}
+
+	if len(b.Transactions) == 0 {
+		if _, err := b.GetTransactions(); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.Price == 0 {
+		if err := b.GetPrice(); err != nil && err != ErrNoPrice {
+			return nil, err
+		}
+	}
+
+	b.Stats = CalcStats(b.Transactions, b.Price)
+	return b.Stats, nil
+}
+
+// GetReward return block reward check, if block was not checked yet
+// reward is calculated from block transactions, use rewards command to store it
+func (b *Block) GetReward() (*Reward, error) {
+	if b.Reward != nil {
+		return b.Reward, nil
+	}
+
+	if len(b.Transactions) == 0 {
+		if _, err := b.GetTransactions(); err != nil {
+			return nil, err
+		}
+	}
+
+	r, err := CalcReward(b)
+	if err != nil {
+		return nil, err
+	}
+
+	b.Reward = r
+	return b.Reward, nil
+}
+
+// Insert will create new record for current block,
+// blocks orphaned by chain reorganization are moved to stale blocks in the same transaction
 func (b *Block) Insert() error {
-	return b.storage.Insert(b)
+	stale, err := HandleReorg(b.storage, b)
+	if err != nil {
+		return err
+	}
+
+	if len(stale) > 0 {
+		log.Printf("block: reorganization at height %d, %d blocks orphaned", b.Height, len(stale))
+	}
+	return nil
 }
 
 // Last10 return last 10 blocks
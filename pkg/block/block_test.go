@@ -1,19 +1,28 @@
 package block
 
 import (
+	"encoding/hex"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/jackc/pgx"
-	"github.com/vladyslav2/bitcoin2sql/pkg/transaction"
+	"github.com/pkg/errors"
+	"github.com/webdeveloppro/cryptopiggy/pkg/network"
+	"github.com/webdeveloppro/cryptopiggy/pkg/transaction"
 )
 
+var rightDate = time.Date(2011, 11, 25, 4, 52, 48, 0, time.UTC)
+
 type FakeStorage struct {
 	resp string
 	code int
 }
 
-func (s FakeStorage) GetByHash(hash string) (*Block, error) {
-	bl := Block{
+func fakeBlock() Block {
+	return Block{
 		ID:             154728,
 		Bits:           437129626,
 		Height:         154724,
@@ -21,9 +30,17 @@ func (s FakeStorage) GetByHash(hash string) (*Block, error) {
 		Version:        1,
 		HashPrevBlock:  "00000000000003bfc715be0afb06486c325c12dea913766564fd7e9bc453889d",
 		HashMerkleRoot: "4eff3116a1a55119f83e829f30761692695837e28abe2d4d2ba1b03aecdbd0d9",
-		CreatedAt:      "2011-11-25 04:52:48",
+		CreatedAt:      rightDate,
 		Hash:           "0000000000000aece46da94d3880c3d43c3da17a1e7f06f5ca199aad9dbbac3e",
+		PrevBlock: &Ref{
+			Height: 154723,
+			Hash:   "00000000000003bfc715be0afb06486c325c12dea913766564fd7e9bc453889d",
+		},
 	}
+}
+
+func (s FakeStorage) GetByHash(hash string) (*Block, error) {
+	bl := fakeBlock()
 
 	if hash == "existhash" {
 		return &bl, nil
@@ -36,25 +53,118 @@ func (s FakeStorage) GetByHash(hash string) (*Block, error) {
 	panic("hash do not match anything, please verify email address")
 }
 
+func (s FakeStorage) GetByHeight(height int32) (*Block, error) {
+	bl := fakeBlock()
+
+	if height == bl.Height {
+		return &bl, nil
+	}
+
+	return &Block{}, pgx.ErrNoRows
+}
+
+func (s FakeStorage) GetByTime(t time.Time, mode TimeMode) (*Block, error) {
+	bl := fakeBlock()
+	if mode == TimeAfter {
+		bl.Height = 100
+	}
+	return &bl, nil
+}
+
+func (s FakeStorage) Tip() (*Block, error) {
+	bl := fakeBlock()
+	bl.Height += 2
+	bl.Hash = "tiphash"
+	return &bl, nil
+}
+
+func (s FakeStorage) Orphan(height int32) ([]Block, error) {
+	tip, _ := s.Tip()
+	return make([]Block, tip.Height-height), nil
+}
+
 func (s FakeStorage) Insert(*Block) error {
 	return nil
 }
 
+func (s FakeStorage) InsertStale(*Block) error {
+	return nil
+}
+
+func (s FakeStorage) Reorganize(height int32, b *Block) ([]Block, error) {
+	if b.Hash == "badblock" {
+		return nil, ErrNoTransactions
+	}
+	return s.Orphan(height)
+}
+
 func (s FakeStorage) Last10() ([]Block, error) {
 	return make([]Block, 5), nil
 }
 
-func (s FakeStorage) getTransactions(int) ([]transaction.Transaction, error) {
+func (s FakeStorage) getTransactions(uint) ([]transaction.Transaction, error) {
 	return make([]transaction.Transaction, 15), nil
 }
 
-func (s FakeStorage) getPrice(CreatedAt string) (float32, error) {
-	if CreatedAt == "right_date" {
+func (s FakeStorage) getPrice(createdAt time.Time) (float32, error) {
+	if createdAt.Equal(rightDate) {
 		return 100.00, nil
 	}
 	return 0, ErrNoPrice
 }
 
+func (s FakeStorage) List(f Filter) ([]Block, error) {
+	blocks := make([]Block, 0)
+	for i := 0; i < 30 && i <= f.Limit; i++ {
+		blocks = append(blocks, Block{Height: int32(100 - i)})
+	}
+	return blocks, nil
+}
+
+func (s FakeStorage) Retargets() ([]Retarget, error) {
+	return make([]Retarget, 3), nil
+}
+
+func (s FakeStorage) getTxOut(hash string, index uint32) (*transaction.TxOut, error) {
+	// legacy nonstandard address 5 owns second output of prevtx
+	if hash == "prevtx" && index < 2 {
+		outs := []transaction.TxOut{
+			{PkScript: "76a914d4acec9b747f438152505781406b958548d1b62a88ac", Value: 100, AddressID: 1},
+			{PkScript: "0101ac", Value: 200, AddressID: 5, Addresses: []string{"nonstandard-0101ac"}},
+		}
+		return &outs[index], nil
+	}
+
+	// output imported before address_id was stored
+	if hash == "oldprevtx" && index == 0 {
+		return &transaction.TxOut{PkScript: "0101ac", Value: 300}, nil
+	}
+	return nil, ErrNoPrevOut
+}
+
+func (s FakeStorage) NonstandardAddresses() ([]uint, error) {
+	return []uint{5}, nil
+}
+
+func (s FakeStorage) RekeyAddress(id uint) (int, error) {
+	return 0, nil
+}
+
+func (s FakeStorage) getPools() ([]Pool, error) {
+	return []Pool{
+		{Name: "Slush", Tags: []string{"/slush/"}},
+		{Name: "Satoshi", Addresses: []string{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa"}},
+	}, nil
+}
+
+func (s FakeStorage) PoolShares(period string, from time.Time, to time.Time) ([]PoolShare, error) {
+	return []PoolShare{
+		{Period: rightDate, Pool: "Slush", Blocks: 3},
+		{Period: rightDate, Pool: UnknownPool, Blocks: 1},
+		{Period: rightDate.AddDate(0, 1, 0), Pool: "Slush", Blocks: 5},
+	}, nil
+}
+
 func TestGetByHash(t *testing.T) {
 	t.Parallel()
 	s := FakeStorage{}
@@ -70,6 +180,67 @@ func TestGetByHash(t *testing.T) {
 	}
 }
 
+func TestGetByHeight(t *testing.T) {
+	t.Parallel()
+	s := FakeStorage{}
+
+	b, err := s.GetByHeight(154724)
+	if b.Hash != "0000000000000aece46da94d3880c3d43c3da17a1e7f06f5ca199aad9dbbac3e" {
+		t.Errorf("GetByHeight cant find a block but should, block: %v, err: %v", b, err)
+	}
+
+	if b.PrevBlock == nil || b.PrevBlock.Hash != b.HashPrevBlock {
+		t.Errorf("GetByHeight return wrong prev block reference, %+v", b.PrevBlock)
+	}
+
+	_, err = s.GetByHeight(1)
+	if err != pgx.ErrNoRows {
+		t.Errorf("GetByHeight return wrong error for not existing block, %v", err)
+	}
+}
+
+func TestHandleReorg(t *testing.T) {
+	t.Parallel()
+	s := FakeStorage{}
+
+	b := New(s)
+	b.HashPrevBlock = "tiphash"
+	stale, err := HandleReorg(s, b)
+	if err != nil || len(stale) != 0 {
+		t.Errorf("HandleReorg should not orphan blocks for chain tip child, stale: %v, err: %v", stale, err)
+	}
+
+	tip, _ := s.Tip()
+	b.HashPrevBlock = "existhash"
+	b.Height = tip.Height + 1
+	stale, err = HandleReorg(s, b)
+	if err != nil || len(stale) != 2 {
+		t.Errorf("HandleReorg should orphan 2 blocks, stale: %v, err: %v", stale, err)
+	}
+
+	// equal height competitor is saved as stale, fake Reorganize fails for badblock
+	competitor := New(s)
+	competitor.Hash = "badblock"
+	competitor.HashPrevBlock = "existhash"
+	competitor.Height = tip.Height
+	stale, err = HandleReorg(s, competitor)
+	if err != nil || stale != nil {
+		t.Errorf("HandleReorg should keep first seen block for equal height competitor, stale: %v, err: %v", stale, err)
+	}
+
+	b.Hash = "badblock"
+	stale, err = HandleReorg(s, b)
+	if errors.Cause(err) != ErrNoTransactions || stale != nil {
+		t.Errorf("HandleReorg should return insert error without orphaned blocks, stale: %v, err: %v", stale, err)
+	}
+
+	b.HashPrevBlock = "notexisthash"
+	_, err = HandleReorg(s, b)
+	if err != ErrUnknownParent {
+		t.Errorf("HandleReorg return wrong error for unknown parent, should: %v, got: %v", ErrUnknownParent, err)
+	}
+}
+
 func TestLast10(t *testing.T) {
 	t.Parallel()
 
@@ -98,16 +269,581 @@ func TestGetTransactions(t *testing.T) {
 func TestGetPrice(t *testing.T) {
 	t.Parallel()
 	b := New(FakeStorage{})
-	b.CreatedAt = "right_date"
+	b.CreatedAt = rightDate
 
 	err := b.GetPrice()
 	if b.Price != 100.00 {
 		t.Errorf("getPrice return wrong amount should 100.00, got: %v", b.Price)
 	}
 
-	b.CreatedAt = "not_right_date"
+	b.CreatedAt = time.Time{}
 	err = b.GetPrice()
 	if err != ErrNoPrice {
 		t.Errorf("getPrice return wrong err message, should: %v, got: %v", ErrNoPrice, err)
 	}
 }
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	genesis := Block{
+		Bits:           486604799,
+		Height:         0,
+		Nonce:          2083236893,
+		Version:        1,
+		HashPrevBlock:  "0000000000000000000000000000000000000000000000000000000000000000",
+		HashMerkleRoot: "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b",
+		CreatedAt:      time.Unix(1231006505, 0).UTC(),
+		Hash:           "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f",
+		Transactions: []transaction.Transaction{
+			{ID: 1, Hash: "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"},
+		},
+	}
+
+	if err := Verify(&genesis); err != nil {
+		t.Errorf("Verify return error for genesis block: %v", err)
+	}
+
+	genesis.Nonce++
+	if err := Verify(&genesis); errors.Cause(err) != ErrHashMismatch {
+		t.Errorf("Verify return wrong error for changed nonce, should: %v, got: %v", ErrHashMismatch, err)
+	}
+	genesis.Nonce--
+
+	genesis.Transactions = append(genesis.Transactions, transaction.Transaction{
+		ID:   2,
+		Hash: "0e3e2357e806b6cdb1f70b54c3a3a17b6714ee1f0e68bebb44a74b1efd512098",
+	})
+	if err := Verify(&genesis); errors.Cause(err) != ErrMerkleMismatch {
+		t.Errorf("Verify return wrong error for extra transaction, should: %v, got: %v", ErrMerkleMismatch, err)
+	}
+}
+
+func TestMerkleRoot(t *testing.T) {
+	t.Parallel()
+
+	// block 170, transactions are given in reverse order like FindTransactions return them
+	root, err := MerkleRoot([]transaction.Transaction{
+		{ID: 172, Hash: "f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16"},
+		{ID: 171, Hash: "b1fea52486ce0c62bb442b530a3f0132b826c74e473d1f2c220bfa78111c5082"},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if root.String() != "7dac2c5666815c17a3b36427de37bb9d2e2c5ccec3f8633eb91a4205cb4c10ff" {
+		t.Errorf("MerkleRoot return wrong hash for block 170, got: %s", root)
+	}
+
+	if _, err := MerkleRoot(nil); err != ErrNoTransactions {
+		t.Errorf("MerkleRoot return wrong error for empty block, should: %v, got: %v", ErrNoTransactions, err)
+	}
+}
+
+func TestCalcStats(t *testing.T) {
+	t.Parallel()
+
+	trans := []transaction.Transaction{
+		{
+			Hash:   "coinbase",
+			TxIns:  []transaction.TxIn{{}},
+			TxOuts: []transaction.TxOut{{Value: 5000001000}},
+		},
+		{
+			Hash:   "small",
+			TxIns:  []transaction.TxIn{{PrevOut: "a", Amount: 100000000}},
+			TxOuts: []transaction.TxOut{{Value: 99999500}},
+		},
+		{
+			Hash:   "big",
+			TxIns:  []transaction.TxIn{{PrevOut: "b", Amount: 6000000000}, {PrevOut: "c", Amount: 500}},
+			TxOuts: []transaction.TxOut{{Value: 5000000000}, {Value: 1000000000}},
+		},
+	}
+
+	s := CalcStats(trans, 100.00)
+	if s.TxCount != 3 {
+		t.Errorf("CalcStats return wrong tx count should 3, got: %d", s.TxCount)
+	}
+
+	if s.TotalFee != 1000 {
+		t.Errorf("CalcStats return wrong fee should 1000, got: %d", s.TotalFee)
+	}
+
+	if s.TotalIn != 6100000500 || s.TotalOut != 11100000500 {
+		t.Errorf("CalcStats return wrong totals, in: %d, out: %d", s.TotalIn, s.TotalOut)
+	}
+
+	if s.LargestTx != "big" || s.LargestTxValue != 6000000000 {
+		t.Errorf("CalcStats return wrong largest transaction, %s: %d", s.LargestTx, s.LargestTxValue)
+	}
+
+	if s.LargestTxUSD != 6000.00 {
+		t.Errorf("CalcStats return wrong largest transaction usd should 6000.00, got: %f", s.LargestTxUSD)
+	}
+}
+
+func TestDifficulty(t *testing.T) {
+	t.Parallel()
+
+	if d := Difficulty(486604799); d != 1 {
+		t.Errorf("Difficulty for genesis bits should 1, got: %f", d)
+	}
+
+	if d := Difficulty(437129626); d < 1192497 || d > 1192498 {
+		t.Errorf("Difficulty for bits 437129626 should 1192497.75, got: %f", d)
+	}
+
+	if d := Difficulty(0); d != 0 {
+		t.Errorf("Difficulty for empty bits should 0, got: %f", d)
+	}
+}
+
+func TestRetargetCalc(t *testing.T) {
+	t.Parallel()
+
+	r := Retarget{
+		Blocks:    RetargetInterval,
+		Bits:      486604799,
+		StartedAt: rightDate,
+		EndedAt:   rightDate.Add(600 * time.Second * (RetargetInterval - 1)),
+	}
+	r.calc()
+
+	if r.AvgInterval != 600 {
+		t.Errorf("Retarget average interval should 600, got: %f", r.AvgInterval)
+	}
+
+	if r.Hashrate < 7158278 || r.Hashrate > 7158279 {
+		t.Errorf("Retarget hashrate for difficulty 1 should 7158278.8, got: %f", r.Hashrate)
+	}
+}
+
+func TestRetargets(t *testing.T) {
+	t.Parallel()
+
+	retargets, _ := Retargets(FakeStorage{})
+	if len(retargets) != 3 {
+		t.Errorf("Retargets return wrong amount should 3, got: %v", retargets)
+	}
+}
+
+func TestFromWire(t *testing.T) {
+	t.Parallel()
+
+	coinbase := chaincfg.MainNetParams.GenesisBlock.Transactions[0].Copy()
+	pkScript, _ := hex.DecodeString("76a914d4acec9b747f438152505781406b958548d1b62a88ac")
+
+	spend := wire.NewMsgTx(1)
+	spend.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: coinbase.TxHash(), Index: 0}, []byte{0x01, 0x02}, nil))
+	spend.AddTxOut(wire.NewTxOut(4999990000, pkScript))
+
+	msg := wire.NewMsgBlock(&chaincfg.MainNetParams.GenesisBlock.Header)
+	msg.AddTransaction(coinbase)
+	msg.AddTransaction(spend)
+
+	b, err := FromWire(FakeStorage{}, msg, 0)
+	if err != nil {
+		t.Fatalf("FromWire return error: %v", err)
+	}
+
+	if b.Hash != "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f" {
+		t.Errorf("FromWire return wrong block hash, got: %s", b.Hash)
+	}
+
+	if len(b.Transactions) != 2 || !b.Transactions[0].IsCoinbase() {
+		t.Fatalf("FromWire return wrong transactions, %+v", b.Transactions)
+	}
+
+	if b.Transactions[0].TxOuts[0].Addresses[0] != "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa" {
+		t.Errorf("FromWire return wrong coinbase address, %v", b.Transactions[0].TxOuts[0].Addresses)
+	}
+
+	in := b.Transactions[1].TxIns[0]
+	if in.Amount != 5000000000 || in.AddressID != 0 || in.Address != "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa" || in.ScriptType != transaction.ScriptP2PK {
+		t.Errorf("FromWire return wrong resolved input, %+v", in)
+	}
+
+	hashes, err := addressHashes(b)
+	if err != nil || len(hashes) != 2 || hashes[0] != "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa" {
+		t.Errorf("addressHashes return wrong addresses, %v, %v", hashes, err)
+	}
+
+	setAddressIDs(b, map[string]uint{hashes[0]: 1, hashes[1]: 2})
+	if in := b.Transactions[1].TxIns[0]; in.AddressID != 1 || b.Transactions[1].TxOuts[0].AddressID != 2 {
+		t.Errorf("setAddressIDs return wrong address ids, %+v, %+v", in, b.Transactions[1].TxOuts[0])
+	}
+
+	if len(b.Transactions[1].Addresses) != 2 || len(b.Transactions[0].Addresses) != 1 {
+		t.Errorf("setAddressIDs return wrong transaction addresses should 2, got: %v", b.Transactions[1].Addresses)
+	}
+
+	msg.Transactions = msg.Transactions[1:]
+	if _, err := FromWire(FakeStorage{}, msg, 0); errors.Cause(err) != ErrNoPrevOut {
+		t.Errorf("FromWire return wrong error for unknown input, should: %v, got: %v", ErrNoPrevOut, err)
+	}
+}
+
+func TestBalanceChanges(t *testing.T) {
+	t.Parallel()
+
+	b := New(FakeStorage{})
+	b.CreatedAt = rightDate
+	b.Transactions = []transaction.Transaction{
+		{
+			ID:     10,
+			TxIns:  []transaction.TxIn{{}},
+			TxOuts: []transaction.TxOut{{Value: 5000000000, AddressID: 3}},
+		},
+		{
+			ID:     11,
+			TxIns:  []transaction.TxIn{{PrevOut: "a", Amount: 700, AddressID: 3}},
+			TxOuts: []transaction.TxOut{{Value: 600, AddressID: 1}, {Value: 50, AddressID: 3}},
+		},
+	}
+
+	rows, changes := balanceChanges(b)
+	if len(rows) != 4 {
+		t.Errorf("balanceChanges return wrong amount of log rows should 4, got: %v", rows)
+	}
+
+	if len(changes) != 2 || changes[0].addressID != 1 || changes[1].addressID != 3 {
+		t.Fatalf("balanceChanges return wrong addresses, %+v", changes)
+	}
+
+	if changes[1].income != 5000000050 || changes[1].outcome != 700 {
+		t.Errorf("balanceChanges return wrong change for address 3, %+v", changes[1])
+	}
+
+	if rows[1][1].(int64) != -700 {
+		t.Errorf("balanceChanges should log outcome as negative amount, got: %v", rows[1])
+	}
+}
+
+func TestUtxoChanges(t *testing.T) {
+	t.Parallel()
+
+	b := New(FakeStorage{})
+	b.Height = 200
+	b.Transactions = []transaction.Transaction{
+		{
+			Hash:   "cb",
+			TxIns:  []transaction.TxIn{{}},
+			TxOuts: []transaction.TxOut{{Value: 5000000000, AddressID: 3}, {PkScript: "6a24aa21a9ed"}},
+		},
+		{
+			Hash:   "tx",
+			TxIns:  []transaction.TxIn{{PrevOut: "a", PrevIndex: 2}, {PrevOut: "cb"}},
+			TxOuts: []transaction.TxOut{{Value: 600, AddressID: 1}},
+		},
+	}
+
+	rows, hashes, vouts := utxoChanges(b, 75.5)
+	if len(rows) != 2 {
+		t.Fatalf("utxoChanges should skip OP_RETURN output and return 2 rows, got: %v", rows)
+	}
+
+	if rows[1][0] != "tx" || rows[1][1] != int32(0) || rows[1][3] != int64(600) || rows[1][4] != int32(200) || rows[1][5] != float32(75.5) {
+		t.Errorf("utxoChanges return wrong row, %v", rows[1])
+	}
+
+	if len(hashes) != 2 || hashes[0] != "a" || vouts[0] != 2 || hashes[1] != "cb" || vouts[1] != 0 {
+		t.Errorf("utxoChanges return wrong spent outpoints, %v %v", hashes, vouts)
+	}
+}
+
+func TestUtxoDuplicateCoinbase(t *testing.T) {
+	t.Parallel()
+
+	// block 91842 coinbase has the same txid as block 91812 coinbase
+	coinbase := func(height int32) *Block {
+		b := New(FakeStorage{})
+		b.Height = height
+		b.Transactions = []transaction.Transaction{{
+			Hash:   "e3bf3d07d4b0375638d5f1db5255fe07ba2c4cb067cd81b84ee974b6585fb468",
+			TxIns:  []transaction.TxIn{{}},
+			TxOuts: []transaction.TxOut{{Value: 5000000000, AddressID: 3}},
+		}}
+		return b
+	}
+
+	older, _, _ := utxoChanges(coinbase(91812), 0)
+	later, spent, _ := utxoChanges(coinbase(91842), 0)
+	if len(later) != 1 || len(spent) != 0 {
+		t.Fatalf("utxoChanges return wrong rows for coinbase, %v, %v", later, spent)
+	}
+
+	if later[0][0] != older[0][0] || later[0][1] != older[0][1] || later[0][4] != int32(91842) {
+		t.Errorf("utxoChanges should return the same outpoint with later height, %v, %v", older[0], later[0])
+	}
+}
+
+func TestRekeyTransaction(t *testing.T) {
+	t.Parallel()
+
+	if !IsLegacyNonstandard("nonstandard-0101ac") || IsLegacyNonstandard("nonstandard-"+strings.Repeat("ab", 32)) {
+		t.Errorf("IsLegacyNonstandard should match only old 22 chars identifiers")
+	}
+
+	tr := transaction.Transaction{
+		ID:        20,
+		Hash:      "spend",
+		TxIns:     []transaction.TxIn{{PrevOut: "prevtx", Amount: 200, AddressID: 5}},
+		TxOuts:    []transaction.TxOut{{PkScript: "0102ac", Value: 150, AddressID: 5}, {Value: 40, AddressID: 1}},
+		Addresses: []uint{5, 1},
+	}
+
+	addressID := func(hash string) (uint, error) {
+		if hash == "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa" {
+			return 1, nil
+		}
+		return 2, nil
+	}
+
+	old := legacyAddress{id: 5, hash: "nonstandard-0101ac"}
+	rows, err := rekeyTransaction(FakeStorage{}, addressID, &tr, old, rightDate)
+	if err != nil {
+		t.Fatalf("rekeyTransaction return error: %v", err)
+	}
+
+	// legacy input without prev_index point to output 0, output 1 is found by address and value
+	if tr.TxIns[0].AddressID != 2 || !strings.HasPrefix(tr.TxIns[0].Address, "nonstandard-") || len(tr.TxIns[0].Address) != 76 {
+		t.Errorf("rekeyTransaction return wrong input address, %+v", tr.TxIns[0])
+	}
+
+	if tr.TxOuts[0].AddressID != 2 || tr.TxOuts[0].Addresses[0] == tr.TxIns[0].Address {
+		t.Errorf("rekeyTransaction should key different scripts by different hash, %+v", tr.TxOuts[0])
+	}
+
+	if len(rows) != 2 || rows[0][1] != int64(-200) || rows[1][1] != int64(150) {
+		t.Errorf("rekeyTransaction return wrong address log rows, %v", rows)
+	}
+
+	if len(tr.Addresses) != 2 || tr.Addresses[0] != 1 || tr.Addresses[1] != 2 {
+		t.Errorf("rekeyTransaction return wrong transaction addresses, %v", tr.Addresses)
+	}
+
+	changes := logChanges(rows)
+	if len(changes) != 1 || changes[0].income != 150 || changes[0].outcome != 200 {
+		t.Errorf("logChanges return wrong changes, %+v", changes)
+	}
+
+	// rows without address_id are matched by legacy identifier of script
+	tr = transaction.Transaction{
+		ID:        21,
+		Hash:      "oldspend",
+		TxIns:     []transaction.TxIn{{PrevOut: "oldprevtx", Amount: 300, Address: "nonstandard-0101ac"}},
+		TxOuts:    []transaction.TxOut{{PkScript: "0101ac", Value: 250}, {PkScript: "0102ac", Value: 40}},
+		Addresses: []uint{5},
+	}
+
+	rows, err = rekeyTransaction(FakeStorage{}, addressID, &tr, old, rightDate)
+	if err != nil {
+		t.Fatalf("rekeyTransaction return error for rows without address id: %v", err)
+	}
+
+	if tr.TxIns[0].AddressID != 2 || tr.TxOuts[0].AddressID != 2 || tr.TxOuts[1].AddressID != 0 {
+		t.Errorf("rekeyTransaction should rekey only legacy script, %+v, %+v", tr.TxIns[0], tr.TxOuts)
+	}
+
+	if len(rows) != 2 || rows[0][1] != int64(-300) || rows[1][1] != int64(250) {
+		t.Errorf("rekeyTransaction return wrong address log rows, %v", rows)
+	}
+
+	// old scheme keyed OP_RETURN outputs, now they have no address
+	tr = transaction.Transaction{
+		ID:        22,
+		Hash:      "opreturn",
+		TxOuts:    []transaction.TxOut{{PkScript: "6a0401020304ac", Value: 0, AddressID: 6}},
+		Addresses: []uint{6},
+	}
+
+	rows, err = rekeyTransaction(FakeStorage{}, addressID, &tr, legacyAddress{id: 6, hash: "nonstandard-6a0401020304ac"}, rightDate)
+	if err != nil || len(rows) != 0 || tr.TxOuts[0].AddressID != 0 || len(tr.Addresses) != 0 {
+		t.Errorf("rekeyTransaction should drop address of OP_RETURN output, %+v, %v, %v", tr, rows, err)
+	}
+}
+
+func TestList(t *testing.T) {
+	t.Parallel()
+
+	page, err := List(FakeStorage{}, Filter{})
+	if err != nil || len(page.Blocks) != DefaultPageSize {
+		t.Errorf("List return wrong amount should %d, got: %d, err: %v", DefaultPageSize, len(page.Blocks), err)
+	}
+
+	if page.NextCursor == nil || *page.NextCursor != 81 {
+		t.Errorf("List return wrong next cursor should 81, got: %v", page.NextCursor)
+	}
+
+	page, _ = List(FakeStorage{}, Filter{Limit: 50})
+	if len(page.Blocks) != 30 || page.NextCursor != nil {
+		t.Errorf("List should return last page without cursor, got: %d blocks, cursor: %v", len(page.Blocks), page.NextCursor)
+	}
+
+	height := int32(10)
+	if _, err := List(FakeStorage{}, Filter{Before: &height, After: &height}); err != ErrBadCursor {
+		t.Errorf("List return wrong error for both cursors, should: %v, got: %v", ErrBadCursor, err)
+	}
+}
+
+func TestNearest(t *testing.T) {
+	t.Parallel()
+
+	before := &Block{Height: 1, CreatedAt: rightDate}
+	after := &Block{Height: 2, CreatedAt: rightDate.Add(10 * time.Minute)}
+
+	if b := nearest(rightDate.Add(3*time.Minute), before, after); b.Height != 1 {
+		t.Errorf("nearest should return block before, got: %d", b.Height)
+	}
+
+	if b := nearest(rightDate.Add(7*time.Minute), before, after); b.Height != 2 {
+		t.Errorf("nearest should return block after, got: %d", b.Height)
+	}
+
+	if b := nearest(rightDate, nil, after); b.Height != 2 {
+		t.Errorf("nearest should return block after when before is missing, got: %d", b.Height)
+	}
+
+	if b := nearest(rightDate, nil, nil); b != nil {
+		t.Errorf("nearest should return nil without blocks, got: %v", b)
+	}
+}
+
+func TestParseTimeMode(t *testing.T) {
+	t.Parallel()
+
+	if mode, err := ParseTimeMode(""); mode != TimeNearest || err != nil {
+		t.Errorf("ParseTimeMode should return nearest for empty mode, got: %s, %v", mode, err)
+	}
+
+	if _, err := ParseTimeMode("around"); err != ErrBadTimeMode {
+		t.Errorf("ParseTimeMode return wrong error, should: %v, got: %v", ErrBadTimeMode, err)
+	}
+}
+
+func TestHeightRange(t *testing.T) {
+	t.Parallel()
+
+	from, to, err := HeightRange(FakeStorage{}, rightDate, rightDate)
+	if err != nil || from != 100 || to != 154724 {
+		t.Errorf("HeightRange return wrong range 100-154724, got: %d-%d, %v", from, to, err)
+	}
+}
+
+func TestAttribute(t *testing.T) {
+	t.Parallel()
+
+	pools, _ := FakeStorage{}.getPools()
+	b := New(FakeStorage{})
+	b.Version = 2
+	b.Transactions = []transaction.Transaction{{
+		TxIns:  []transaction.TxIn{{SignatureScript: "035b7a0300ff2f736c7573682f004d696e6564206279206d65"}},
+		TxOuts: []transaction.TxOut{{PkScript: "76a914d4acec9b747f438152505781406b958548d1b62a88ac"}},
+	}}
+
+	m, err := Attribute(b, pools)
+	if err != nil || m.Pool != "Slush" {
+		t.Errorf("Attribute should find Slush pool by tag, got: %+v, %v", m, err)
+	}
+
+	if m.Height == nil || *m.Height != 227931 {
+		t.Errorf("Attribute return wrong BIP34 height, got: %v", m.Height)
+	}
+
+	genesis := New(FakeStorage{})
+	genesis.Version = 1
+	genesis.Transactions = []transaction.Transaction{{
+		TxIns:  []transaction.TxIn{{SignatureScript: "04ffff001d0104"}},
+		TxOuts: []transaction.TxOut{{PkScript: "4104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac"}},
+	}}
+
+	m, err = Attribute(genesis, pools)
+	if err != nil || m.Pool != "Satoshi" || m.Height != nil {
+		t.Errorf("Attribute should find pool by address without height, got: %+v, %v", m, err)
+	}
+
+	m, _ = Attribute(genesis, nil)
+	if m.Pool != UnknownPool {
+		t.Errorf("Attribute should return unknown pool, got: %s", m.Pool)
+	}
+}
+
+func TestPoolShares(t *testing.T) {
+	t.Parallel()
+
+	shares, err := PoolShares(FakeStorage{}, "", time.Time{}, time.Time{})
+	if err != nil || len(shares) != 3 {
+		t.Fatalf("PoolShares return wrong result, %v, %v", shares, err)
+	}
+
+	if shares[0].Share != 0.75 || shares[2].Share != 1 {
+		t.Errorf("PoolShares return wrong shares, %+v", shares)
+	}
+
+	if _, err := PoolShares(FakeStorage{}, "hour", time.Time{}, time.Time{}); err != ErrBadPeriod {
+		t.Errorf("PoolShares return wrong error, should: %v, got: %v", ErrBadPeriod, err)
+	}
+}
+
+func TestSubsidy(t *testing.T) {
+	t.Parallel()
+
+	for height, subsidy := range map[int32]int64{
+		0:       5000000000,
+		209999:  5000000000,
+		210000:  2500000000,
+		420000:  1250000000,
+		6930000: 0,
+	} {
+		if s := Subsidy(height); s != subsidy {
+			t.Errorf("Subsidy for height %d should %d, got: %d", height, subsidy, s)
+		}
+	}
+}
+
+func TestSubsidyRegtest(t *testing.T) {
+	defer network.Set("mainnet")
+
+	if err := network.Set("regtest"); err != nil {
+		t.Fatalf("Cannot select regtest network, %v", err)
+	}
+
+	// regtest halves every 150 blocks
+	if Subsidy(150) != 2500000000 {
+		t.Errorf("Subsidy return wrong regtest amount should 2500000000, got: %d", Subsidy(150))
+	}
+}
+
+func TestCalcReward(t *testing.T) {
+	t.Parallel()
+
+	b := New(FakeStorage{})
+	b.Height = 210000
+	b.Transactions = []transaction.Transaction{
+		{
+			TxIns:  []transaction.TxIn{{}},
+			TxOuts: []transaction.TxOut{{Value: 2500000000}},
+		},
+		{
+			TxIns:  []transaction.TxIn{{PrevOut: "a", Amount: 1000}},
+			TxOuts: []transaction.TxOut{{Value: 900}},
+		},
+	}
+
+	r, err := CalcReward(b)
+	if err != nil || r.Unclaimed != 100 || !r.Valid() {
+		t.Errorf("CalcReward should return 100 unclaimed, got: %+v, %v", r, err)
+	}
+
+	b.Transactions[0].TxOuts[0].Value = 2500000200
+	r, _ = CalcReward(b)
+	if r.Valid() || r.Unclaimed != -100 {
+		t.Errorf("CalcReward should return invalid reward, got: %+v", r)
+	}
+
+	b.Transactions = b.Transactions[1:]
+	if _, err := CalcReward(b); errors.Cause(err) != ErrNoCoinbase {
+		t.Errorf("CalcReward return wrong error, should: %v, got: %v", ErrNoCoinbase, err)
+	}
+}
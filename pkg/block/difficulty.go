@@ -0,0 +1,66 @@
+package block
+
+import (
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+)
+
+// RetargetInterval amount of blocks between difficulty adjustments
+const RetargetInterval = 2016
+
+// powLimitBits is compact target of difficulty 1
+const powLimitBits = 0x1d00ffff
+
+// Retarget holds difficulty and mining numbers for one retarget period
+type Retarget struct {
+	Period      int32     `json:"period"`
+	StartHeight int32     `json:"start_height"`
+	EndHeight   int32     `json:"end_height"`
+	StartedAt   time.Time `json:"started_at"`
+	EndedAt     time.Time `json:"ended_at"`
+	Blocks      int       `json:"blocks"`
+	Bits        uint32    `json:"bits"`
+	Difficulty  float64   `json:"difficulty"`
+	AvgInterval float64   `json:"avg_interval"` // seconds between blocks
+	Hashrate    float64   `json:"hashrate"`     // estimated hashes per second
+}
+
+// Difficulty decode compact bits to difficulty relative to minimal target
+func Difficulty(bits uint32) float64 {
+	target := blockchain.CompactToBig(bits)
+	if target.Sign() <= 0 {
+		return 0
+	}
+
+	diff, _ := new(big.Float).Quo(
+		new(big.Float).SetInt(blockchain.CompactToBig(powLimitBits)),
+		new(big.Float).SetInt(target),
+	).Float64()
+	return diff
+}
+
+// calc fill difficulty, average interval and hashrate from period boundaries
+func (r *Retarget) calc() {
+	r.Difficulty = Difficulty(r.Bits)
+
+	if r.Blocks < 2 {
+		return
+	}
+
+	r.AvgInterval = r.EndedAt.Sub(r.StartedAt).Seconds() / float64(r.Blocks-1)
+	if r.AvgInterval <= 0 {
+		r.AvgInterval = 0
+		return
+	}
+
+	// on average difficulty * 2^32 hashes needed to find a block
+	r.Hashrate = r.Difficulty * math.Pow(2, 32) / r.AvgInterval
+}
+
+// Retargets return difficulty history for every retarget period
+func Retargets(storage Storage) ([]Retarget, error) {
+	return storage.Retargets()
+}
@@ -0,0 +1,61 @@
+package block
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultPageSize amount of blocks returned when limit is not set
+const DefaultPageSize = 20
+
+// MaxPageSize maximum amount of blocks per page
+const MaxPageSize = 100
+
+// ErrBadCursor error for requests with both before and after cursors
+var ErrBadCursor = fmt.Errorf("Only one of before or after cursor can be used")
+
+// Filter holds block listing parameters, nil cursor and zero time are ignored
+type Filter struct {
+	Before *int32    // blocks with height lower than before, newest first
+	After  *int32    // blocks with height greater than after, oldest first
+	From   time.Time // blocks created at or after from
+	To     time.Time // blocks created before to
+	Limit  int
+}
+
+// Page holds one page of blocks and cursor for the next page
+type Page struct {
+	Blocks     []Block `json:"blocks"`
+	NextCursor *int32  `json:"next_cursor"`
+}
+
+// List return page of blocks according to filter,
+// next cursor is a height to use in the same before/after parameter
+func List(storage Storage, f Filter) (Page, error) {
+	page := Page{Blocks: make([]Block, 0)}
+
+	if f.Before != nil && f.After != nil {
+		return page, ErrBadCursor
+	}
+
+	if f.Limit <= 0 {
+		f.Limit = DefaultPageSize
+	} else if f.Limit > MaxPageSize {
+		f.Limit = MaxPageSize
+	}
+
+	blocks, err := storage.List(f)
+	if err != nil {
+		return page, err
+	}
+
+	// storage return one extra block to show that next page exists
+	if len(blocks) > f.Limit {
+		blocks = blocks[:f.Limit]
+		next := blocks[len(blocks)-1].Height
+		page.NextCursor = &next
+	}
+
+	page.Blocks = blocks
+	return page, nil
+}
@@ -0,0 +1,160 @@
+package block
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrBadPeriod error for unknown pool share period
+var ErrBadPeriod = fmt.Errorf("Period should be day, week, month or year")
+
+// UnknownPool name for blocks which do not match any pool
+const UnknownPool = "unknown"
+
+// bip34Version first block version with height in coinbase
+const bip34Version = 2
+
+// Pool holds rules to recognize mining pool blocks
+type Pool struct {
+	ID        uint     `json:"id"`
+	Name      string   `json:"name"`
+	URL       string   `json:"url"`
+	Tags      []string `json:"tags"`      // case insensitive coinbase text patterns
+	Addresses []string `json:"addresses"` // coinbase payout addresses
+}
+
+// Miner holds coinbase data and pool which mined the block
+type Miner struct {
+	Pool      string   `json:"pool"`
+	Height    *int32   `json:"height"` // BIP34 height from coinbase
+	Tags      []string `json:"tags"`
+	Addresses []string `json:"addresses"`
+}
+
+// PoolShare holds amount of blocks mined by pool in period
+type PoolShare struct {
+	Period time.Time `json:"period"`
+	Pool   string    `json:"pool"`
+	Blocks int       `json:"blocks"`
+	Share  float64   `json:"share"`
+}
+
+// Attribute decode block coinbase and find pool by payout address or coinbase tag,
+// addresses are checked first as tags can be copied by anyone
+func Attribute(b *Block, pools []Pool) (*Miner, error) {
+	m := Miner{
+		Pool:      UnknownPool,
+		Tags:      make([]string, 0),
+		Addresses: make([]string, 0),
+	}
+
+	if len(b.Transactions) == 0 {
+		return &m, ErrNoTransactions
+	}
+
+	coinbase := b.Transactions[0]
+	for _, t := range b.Transactions {
+		if t.IsCoinbase() {
+			coinbase = t
+			break
+		}
+	}
+
+	c, err := coinbase.ParseCoinbase()
+	if err != nil {
+		return &m, errors.Wrapf(err, "block: cannot parse coinbase %s", coinbase.Hash)
+	}
+
+	m.Tags = c.Tags
+	if b.Version >= bip34Version {
+		m.Height = c.Height
+	}
+
+	for i := range coinbase.TxOuts {
+		addrs, err := coinbase.TxOuts[i].GetAddresses()
+		if err != nil {
+			return &m, errors.Wrapf(err, "block: cannot get coinbase addresses %s", coinbase.Hash)
+		}
+		m.Addresses = append(m.Addresses, addrs...)
+	}
+
+	for _, p := range pools {
+		for _, addr := range p.Addresses {
+			for _, payout := range m.Addresses {
+				if addr == payout {
+					m.Pool = p.Name
+					return &m, nil
+				}
+			}
+		}
+	}
+
+	text := strings.ToLower(strings.Join(m.Tags, " "))
+	for _, p := range pools {
+		for _, tag := range p.Tags {
+			if tag != "" && strings.Contains(text, strings.ToLower(tag)) {
+				m.Pool = p.Name
+				return &m, nil
+			}
+		}
+	}
+
+	return &m, nil
+}
+
+// GetMiner return block miner, if block was not attributed yet
+// miner is calculated from coinbase, use miners command to store it
+func (b *Block) GetMiner() (*Miner, error) {
+	if b.Miner != nil {
+		return b.Miner, nil
+	}
+
+	if len(b.Transactions) == 0 {
+		if _, err := b.GetTransactions(); err != nil {
+			return nil, err
+		}
+	}
+
+	pools, err := b.storage.getPools()
+	if err != nil {
+		return nil, errors.Wrap(err, "block: cannot get pools")
+	}
+
+	m, err := Attribute(b, pools)
+	if err != nil {
+		return nil, err
+	}
+
+	b.Miner = m
+	return b.Miner, nil
+}
+
+// PoolShares return share of blocks per pool for every period,
+// period is day, week, month or year
+func PoolShares(storage Storage, period string, from time.Time, to time.Time) ([]PoolShare, error) {
+	switch period {
+	case "":
+		period = "month"
+	case "day", "week", "month", "year":
+	default:
+		return nil, ErrBadPeriod
+	}
+
+	shares, err := storage.PoolShares(period, from, to)
+	if err != nil {
+		return shares, err
+	}
+
+	totals := make(map[time.Time]int)
+	for _, s := range shares {
+		totals[s.Period] += s.Blocks
+	}
+
+	for i := range shares {
+		shares[i].Share = float64(shares[i].Blocks) / float64(totals[shares[i].Period])
+	}
+	return shares, nil
+}
@@ -0,0 +1,155 @@
+package block
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/webdeveloppro/cryptopiggy/pkg/transaction"
+)
+
+// ErrNoAddress error for spent output which has no address to move balance from
+var ErrNoAddress = fmt.Errorf("Output has no address")
+
+// legacyScriptLength is amount of script hex chars old scheme kept in identifier
+const legacyScriptLength = 22
+
+// legacyNonstandardLength is the longest identifier old scheme produced,
+// nonstandard- prefix followed by up to 22 hex chars of the script
+const legacyNonstandardLength = len(transaction.NonstandardPrefix) + legacyScriptLength
+
+// IsLegacyNonstandard return true for address hash made by old nonstandard scheme
+func IsLegacyNonstandard(hash string) bool {
+	return strings.HasPrefix(hash, transaction.NonstandardPrefix) && len(hash) <= legacyNonstandardLength
+}
+
+// legacyHash return identifier old scheme made for nonstandard script
+func legacyHash(pkScript string) string {
+	if len(pkScript) > legacyScriptLength {
+		pkScript = pkScript[:legacyScriptLength]
+	}
+	return transaction.NonstandardPrefix + pkScript
+}
+
+// legacyAddress is nonstandard address being rekeyed. Rows imported before
+// address_id was stored have no id, they are matched by address hash instead
+type legacyAddress struct {
+	id   uint
+	hash string
+}
+
+// ownsInput return true when input spends output of legacy address
+func (a legacyAddress) ownsInput(in *transaction.TxIn) bool {
+	if in.AddressID != 0 {
+		return in.AddressID == a.id
+	}
+	return in.Address == a.hash
+}
+
+// ownsOutput return true when output belongs to legacy address
+func (a legacyAddress) ownsOutput(out *transaction.TxOut) bool {
+	if out.AddressID != 0 {
+		return out.AddressID == a.id
+	}
+	return legacyHash(out.PkScript) == a.hash
+}
+
+// rekeyTransaction move inputs and outputs of legacy nonstandard address to
+// new script based addresses, ids are resolved with addressID function.
+// Return address_log rows for new addresses
+func rekeyTransaction(storage Storage, addressID func(string) (uint, error), t *transaction.Transaction, old legacyAddress, createdAt time.Time) ([][]interface{}, error) {
+	rows := make([][]interface{}, 0)
+
+	for i := range t.TxIns {
+		in := &t.TxIns[i]
+		if !old.ownsInput(in) {
+			continue
+		}
+
+		prev, err := rekeyPrevOut(storage, in, old)
+		if err != nil {
+			return rows, errors.Wrapf(err, "block: cannot resolve input %d of %s, %s", i, t.Hash, in.Outpoint())
+		}
+
+		addrs, err := prev.GetAddresses()
+		if err == nil && len(addrs) == 0 {
+			err = ErrNoAddress
+		}
+		if err != nil {
+			return rows, errors.Wrapf(err, "block: cannot get address for input %d of %s", i, t.Hash)
+		}
+
+		if in.AddressID, err = addressID(addrs[0]); err != nil {
+			return rows, errors.Wrapf(err, "block: cannot get address id for %s", addrs[0])
+		}
+		in.Address = addrs[0]
+		rows = append(rows, []interface{}{in.AddressID, -in.Amount, createdAt, t.ID})
+	}
+
+	for i := range t.TxOuts {
+		out := &t.TxOuts[i]
+		if !old.ownsOutput(out) {
+			continue
+		}
+
+		out.Addresses = nil
+		addrs, err := out.GetAddresses()
+		if err != nil {
+			return rows, errors.Wrapf(err, "block: cannot get address for output %d of %s", i, t.Hash)
+		}
+
+		// OP_RETURN outputs were keyed by old scheme, now they have no address
+		if len(addrs) == 0 {
+			out.AddressID = 0
+			continue
+		}
+
+		if out.AddressID, err = addressID(addrs[0]); err != nil {
+			return rows, errors.Wrapf(err, "block: cannot get address id for %s", addrs[0])
+		}
+		rows = append(rows, []interface{}{out.AddressID, out.Value, createdAt, t.ID})
+	}
+
+	addresses := make([]uint, 0, len(t.Addresses))
+	seen := map[uint]bool{old.id: true}
+	add := func(id uint) {
+		if !seen[id] {
+			seen[id] = true
+			addresses = append(addresses, id)
+		}
+	}
+
+	for _, id := range t.Addresses {
+		add(id)
+	}
+	for _, row := range rows {
+		add(row[0].(uint))
+	}
+	t.Addresses = addresses
+
+	return rows, nil
+}
+
+// rekeyPrevOut return output spent by input with fresh addresses.
+// Inputs imported before prev_index was stored point to output 0,
+// for them output of the legacy address with the same value is searched
+func rekeyPrevOut(storage Storage, in *transaction.TxIn, old legacyAddress) (*transaction.TxOut, error) {
+	prev, err := storage.getTxOut(in.PrevOut, in.PrevIndex)
+	if err == nil && old.ownsOutput(prev) && prev.Value == in.Amount {
+		prev.Addresses = nil
+		return prev, nil
+	}
+
+	for index := uint32(0); ; index++ {
+		out, err := storage.getTxOut(in.PrevOut, index)
+		if err != nil {
+			return nil, err
+		}
+
+		if old.ownsOutput(out) && out.Value == in.Amount {
+			out.Addresses = nil
+			return out, nil
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package block
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx"
+	"github.com/pkg/errors"
+)
+
+// ErrUnknownParent error for blocks which previous block is not in the database
+var ErrUnknownParent = fmt.Errorf("Previous block not found")
+
+// HandleReorg insert block on top of its parent. If block was mined on top
+// of an older block and is higher than chain tip, all blocks after that parent
+// are moved to stale blocks and their transactions are rolled back in the same
+// database transaction as the insert. Block which does not make chain longer
+// is saved to stale blocks and chain is left as is. Returns list of orphaned blocks
+func HandleReorg(storage Storage, b *Block) ([]Block, error) {
+	tip, err := storage.Tip()
+	if err == pgx.ErrNoRows {
+		// empty database, nothing to compare with
+		return nil, storage.Insert(b)
+	} else if err != nil {
+		return nil, errors.Wrap(err, "block: cannot get chain tip")
+	}
+
+	if tip.Hash == b.HashPrevBlock {
+		return nil, storage.Insert(b)
+	}
+
+	parent, err := storage.GetByHash(b.HashPrevBlock)
+	if err == pgx.ErrNoRows {
+		return nil, ErrUnknownParent
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "block: cannot get previous block %s", b.HashPrevBlock)
+	}
+
+	if b.Height == 0 {
+		b.Height = parent.Height + 1
+	}
+
+	// first seen block wins until competing branch becomes longer
+	if b.Height <= tip.Height {
+		if err := storage.InsertStale(b); err != nil {
+			return nil, errors.Wrapf(err, "block: cannot save stale block %s", b.Hash)
+		}
+		log.Printf("block: %s at height %d does not extend chain tip %d, saved as stale", b.Hash, b.Height, tip.Height)
+		return nil, nil
+	}
+
+	stale, err := storage.Reorganize(parent.Height, b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "block: cannot reorganize chain after height %d", parent.Height)
+	}
+
+	return stale, nil
+}
@@ -0,0 +1,58 @@
+package block
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/pkg/errors"
+	"github.com/webdeveloppro/cryptopiggy/pkg/network"
+)
+
+// ErrNoCoinbase error for blocks without coinbase transaction
+var ErrNoCoinbase = fmt.Errorf("Block has no coinbase transaction")
+
+// Reward holds block subsidy, fees and amount miner actually claimed
+type Reward struct {
+	Subsidy   int64 `json:"subsidy"`
+	Fees      int64 `json:"fees"`
+	Claimed   int64 `json:"claimed"`
+	Unclaimed int64 `json:"unclaimed"` // subsidy plus fees minus claimed, negative if miner took too much
+}
+
+// Subsidy return new coins allowed for block at giving height on selected network
+func Subsidy(height int32) int64 {
+	return blockchain.CalcBlockSubsidy(height, network.Params())
+}
+
+// Valid return false if coinbase claimed more than subsidy plus fees
+func (r *Reward) Valid() bool {
+	return r.Unclaimed >= 0
+}
+
+// CalcReward compare coinbase outputs with subsidy and block fees,
+// block transactions should be loaded before call
+func CalcReward(b *Block) (*Reward, error) {
+	if len(b.Transactions) == 0 {
+		return nil, ErrNoTransactions
+	}
+
+	r := Reward{Subsidy: Subsidy(b.Height)}
+	coinbase := false
+
+	for i := range b.Transactions {
+		t := &b.Transactions[i]
+		if t.IsCoinbase() && !coinbase {
+			coinbase = true
+			r.Claimed = t.OutputValue()
+			continue
+		}
+		r.Fees += t.InputValue() - t.OutputValue()
+	}
+
+	if !coinbase {
+		return nil, errors.Wrapf(ErrNoCoinbase, "block %d", b.Height)
+	}
+
+	r.Unclaimed = r.Subsidy + r.Fees - r.Claimed
+	return &r, nil
+}
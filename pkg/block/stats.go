@@ -0,0 +1,59 @@
+package block
+
+import (
+	"github.com/webdeveloppro/cryptopiggy/pkg/transaction"
+)
+
+// satoshiPerBitcoin used to convert values to bitcoins before applying price
+const satoshiPerBitcoin = 1e8
+
+// BlockStats holds aggregated numbers for all block transactions
+type BlockStats struct {
+	TxCount        int     `json:"tx_count"`
+	TotalIn        int64   `json:"total_in"`
+	TotalOut       int64   `json:"total_out"`
+	TotalFee       int64   `json:"total_fee"`
+	LargestTx      string  `json:"largest_tx"`
+	LargestTxValue int64   `json:"largest_tx_value"`
+	Price          float32 `json:"price"`
+	TotalInUSD     float64 `json:"total_in_usd"`
+	TotalOutUSD    float64 `json:"total_out_usd"`
+	TotalFeeUSD    float64 `json:"total_fee_usd"`
+	LargestTxUSD   float64 `json:"largest_tx_usd"`
+}
+
+// CalcStats calculate block statistics from transactions,
+// fees are counted for all transactions except coinbase
+func CalcStats(trans []transaction.Transaction, price float32) *BlockStats {
+	s := BlockStats{
+		TxCount: len(trans),
+		Price:   price,
+	}
+
+	for i := range trans {
+		in := trans[i].InputValue()
+		out := trans[i].OutputValue()
+
+		s.TotalIn += in
+		s.TotalOut += out
+		if !trans[i].IsCoinbase() {
+			s.TotalFee += in - out
+		}
+
+		if s.LargestTx == "" || out > s.LargestTxValue {
+			s.LargestTx = trans[i].Hash
+			s.LargestTxValue = out
+		}
+	}
+
+	s.TotalInUSD = toUSD(s.TotalIn, price)
+	s.TotalOutUSD = toUSD(s.TotalOut, price)
+	s.TotalFeeUSD = toUSD(s.TotalFee, price)
+	s.LargestTxUSD = toUSD(s.LargestTxValue, price)
+	return &s
+}
+
+// toUSD convert satoshi value to dollars
+func toUSD(value int64, price float32) float64 {
+	return float64(value) / satoshiPerBitcoin * float64(price)
+}
@@ -1,7 +1,9 @@
 package block
 
 import (
+	"encoding/json"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/jackc/pgx"
@@ -15,10 +17,23 @@ var ErrNoPrice = fmt.Errorf("No price for coin")
 // Storage is main interface for operations with Block
 type Storage interface {
 	GetByHash(string) (*Block, error)
+	GetByHeight(int32) (*Block, error)
+	GetByTime(time.Time, TimeMode) (*Block, error)
+	Tip() (*Block, error)
 	Insert(*Block) error
+	InsertStale(*Block) error
+	Reorganize(int32, *Block) ([]Block, error)
+	Orphan(int32) ([]Block, error)
+	Retargets() ([]Retarget, error)
+	PoolShares(string, time.Time, time.Time) ([]PoolShare, error)
 	Last10() ([]Block, error)
+	List(Filter) ([]Block, error)
+	NonstandardAddresses() ([]uint, error)
+	RekeyAddress(uint) (int, error)
 	getTransactions(uint) ([]transaction.Transaction, error)
 	getPrice(time.Time) (float32, error)
+	getTxOut(string, uint32) (*transaction.TxOut, error)
+	getPools() ([]Pool, error)
 }
 
 // PGStorage provider that can handle read/write from database
@@ -33,15 +48,43 @@ func NewStorage(pg *pgx.ConnPool) PGStorage {
 	}
 }
 
-// GetByHash pull user from postgresql database
-func (pg *PGStorage) GetByHash(hash string) (*Block, error) {
+// blockColumns is select list shared by every block query,
+// prev/next references are resolved with self joins on hash_prev_block and height
+const blockColumns = `
+		b.id, b.bits, b.height, b.nonce, b.version, b.hash_prev_block, b.hash_merkle_root, b.created_at, b.hash,
+		p.height, p.hash, n.height, n.hash,
+		CASE WHEN s.block_id IS NULL THEN NULL ELSE to_jsonb(s) END,
+		CASE WHEN m.block_id IS NULL THEN NULL ELSE to_jsonb(m) END,
+		CASE WHEN r.block_id IS NULL THEN NULL ELSE to_jsonb(r) END
+		FROM block as b
+		LEFT JOIN block as p ON p.hash = b.hash_prev_block
+		LEFT JOIN block as n ON n.height = b.height + 1
+		LEFT JOIN block_stats as s ON s.block_id = b.id
+		LEFT JOIN block_miner as m ON m.block_id = b.id
+		LEFT JOIN block_reward as r ON r.block_id = b.id`
 
-	bl := Block{storage: pg}
-	if err := pg.con.QueryRow(`
-		SELECT id, bits, height, nonce, version, hash_prev_block, hash_merkle_root, created_at, hash
-		FROM block
-		WHERE hash = $1
-	`, hash).Scan(
+// execer is implemented by both pgx.ConnPool and pgx.Tx
+type execer interface {
+	Exec(string, ...interface{}) (pgx.CommandTag, error)
+}
+
+// queryer is implemented by both pgx.ConnPool and pgx.Tx
+type queryer interface {
+	execer
+	Query(string, ...interface{}) (*pgx.Rows, error)
+}
+
+// rowScanner is implemented by both pgx.Row and pgx.Rows
+type rowScanner interface {
+	Scan(...interface{}) error
+}
+
+// scanBlock fill block structure from a row selected with blockColumns
+func scanBlock(row rowScanner, bl *Block) error {
+	var prevHeight, nextHeight *int32
+	var prevHash, nextHash *string
+
+	if err := row.Scan(
 		&bl.ID,
 		&bl.Bits,
 		&bl.Height,
@@ -51,19 +94,169 @@ func (pg *PGStorage) GetByHash(hash string) (*Block, error) {
 		&bl.HashMerkleRoot,
 		&bl.CreatedAt,
 		&bl.Hash,
+		&prevHeight,
+		&prevHash,
+		&nextHeight,
+		&nextHash,
+		&bl.Stats,
+		&bl.Miner,
+		&bl.Reward,
 	); err != nil {
+		return err
+	}
+
+	bl.Difficulty = Difficulty(bl.Bits)
+
+	if prevHeight != nil && prevHash != nil {
+		bl.PrevBlock = &Ref{Height: *prevHeight, Hash: *prevHash}
+	}
+
+	if nextHeight != nil && nextHash != nil {
+		bl.NextBlock = &Ref{Height: *nextHeight, Hash: *nextHash}
+	}
+	return nil
+}
+
+// GetByHash pull block from postgresql database
+func (pg *PGStorage) GetByHash(hash string) (*Block, error) {
+
+	bl := Block{storage: pg}
+	if err := scanBlock(pg.con.QueryRow(`
+		SELECT `+blockColumns+`
+		WHERE b.hash = $1
+	`, hash), &bl); err != nil {
+		return &bl, err
+	}
+	return &bl, nil
+}
+
+// GetByHeight pull block with giving height from postgresql database
+func (pg *PGStorage) GetByHeight(height int32) (*Block, error) {
+
+	bl := Block{storage: pg}
+	if err := scanBlock(pg.con.QueryRow(`
+		SELECT `+blockColumns+`
+		WHERE b.height = $1
+	`, height), &bl); err != nil {
+		return &bl, err
+	}
+	return &bl, nil
+}
+
+// GetByTime pull block created around giving time
+func (pg *PGStorage) GetByTime(t time.Time, mode TimeMode) (*Block, error) {
+
+	if mode == TimeNearest {
+		before, err := pg.GetByTime(t, TimeBefore)
+		if err != nil && err != pgx.ErrNoRows {
+			return before, err
+		} else if err == pgx.ErrNoRows {
+			before = nil
+		}
+
+		after, err := pg.GetByTime(t, TimeAfter)
+		if err != nil && err != pgx.ErrNoRows {
+			return after, err
+		} else if err == pgx.ErrNoRows {
+			after = nil
+		}
+
+		if b := nearest(t, before, after); b != nil {
+			return b, nil
+		}
+		return &Block{storage: pg}, pgx.ErrNoRows
+	}
+
+	var sql string
+	switch mode {
+	case TimeBefore:
+		sql = `SELECT ` + blockColumns + `
+			WHERE b.created_at <= $1
+			ORDER BY b.created_at DESC, b.height DESC LIMIT 1`
+	case TimeAfter:
+		sql = `SELECT ` + blockColumns + `
+			WHERE b.created_at >= $1
+			ORDER BY b.created_at ASC, b.height ASC LIMIT 1`
+	default:
+		return &Block{storage: pg}, ErrBadTimeMode
+	}
+
+	bl := Block{storage: pg}
+	if err := scanBlock(pg.con.QueryRow(sql, t), &bl); err != nil {
+		return &bl, err
+	}
+	return &bl, nil
+}
+
+// Tip return block with the biggest height
+func (pg *PGStorage) Tip() (*Block, error) {
+
+	bl := Block{storage: pg}
+	if err := scanBlock(pg.con.QueryRow(`
+		SELECT `+blockColumns+`
+		ORDER BY b.height DESC LIMIT 1
+	`), &bl); err != nil {
 		return &bl, err
 	}
 	return &bl, nil
 }
 
-// Insert new block in the database
+// Insert new block in the database, block, its transactions, address
+// ballances and utxo set are written in one pg commit/rollback transaction
 func (pg *PGStorage) Insert(b *Block) error {
+	tx, err := pg.con.Begin()
+	if err != nil {
+		return errors.Wrap(err, "block: cannot begin insert transaction")
+	}
+	defer tx.Rollback()
+
+	if err := pg.insert(tx, b); err != nil {
+		return err
+	}
 
-	// ToDo
-	// Create each block in  pg commit/rollback transaction
-	// BC2SQL.pg.BeginTx()
-	if err := pg.con.QueryRow(`
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "block: cannot commit insert transaction")
+	}
+	return nil
+}
+
+// Reorganize orphan blocks above giving height and insert new block in one
+// database transaction, chain is left untouched if the new block cannot be inserted
+func (pg *PGStorage) Reorganize(height int32, b *Block) ([]Block, error) {
+	tx, err := pg.con.Begin()
+	if err != nil {
+		return nil, errors.Wrap(err, "block: cannot begin reorganize transaction")
+	}
+	defer tx.Rollback()
+
+	stale, err := orphan(tx, height)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pg.insert(tx, b); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "block: cannot commit reorganize transaction")
+	}
+	return stale, nil
+}
+
+// insert write block with transactions, ballances, utxo, stats, miner and reward inside database transaction
+func (pg *PGStorage) insert(tx *pgx.Tx, b *Block) error {
+	price, err := pg.getPrice(b.CreatedAt)
+	if err != nil && err != ErrNoPrice {
+		return errors.Wrap(err, "block: cannot get price for stats")
+	}
+
+	pools, err := pg.getPools()
+	if err != nil {
+		return errors.Wrap(err, "block: cannot get pools")
+	}
+
+	if err := tx.QueryRow(`
 		INSERT INTO block
 			(bits, height, nonce, version, hash_prev_block, hash_merkle_root, created_at, hash)
 		VALUES
@@ -91,11 +284,84 @@ func (pg *PGStorage) Insert(b *Block) error {
 		return err
 	}
 
-	for _, t := range b.Transactions {
-		t.BlockID = b.ID
-		if err := t.Insert(); err != nil {
-			return err
-		}
+	hashes, err := addressHashes(b)
+	if err != nil {
+		return err
+	}
+
+	ids, err := addressIDs(tx, hashes)
+	if err != nil {
+		return err
+	}
+	setAddressIDs(b, ids)
+
+	for i := range b.Transactions {
+		b.Transactions[i].BlockID = b.ID
+	}
+
+	if err := transaction.CopyFrom(tx, b.Transactions); err != nil {
+		return errors.Wrap(err, "block: insert transactions failed")
+	}
+
+	logRows, changes := balanceChanges(b)
+	if _, err := tx.CopyFrom(
+		pgx.Identifier{"address_log"},
+		[]string{"address_id", "amount", "created_at", "transaction_id"},
+		pgx.CopyFromRows(logRows),
+	); err != nil {
+		return errors.Wrap(err, "block: insert address log failed")
+	}
+
+	if err := updateBalances(tx, changes); err != nil {
+		return err
+	}
+
+	// blocks 91842 and 91880 repeat coinbase txids of blocks 91812 and 91722,
+	// rows are staged and upserted, so the later output replaces the older one
+	utxoRows, spentHashes, spentVouts := utxoChanges(b, price)
+	if _, err := tx.Exec(`CREATE TEMP TABLE utxo_stage (LIKE utxo) ON COMMIT DROP`); err != nil {
+		return errors.Wrap(err, "block: cannot create utxo stage")
+	}
+
+	if _, err := tx.CopyFrom(
+		pgx.Identifier{"utxo_stage"},
+		[]string{"hash", "vout", "address_id", "value", "height", "price"},
+		pgx.CopyFromRows(utxoRows),
+	); err != nil {
+		return errors.Wrap(err, "block: insert utxo failed")
+	}
+
+	if _, err := tx.Exec(utxoUpsert); err != nil {
+		return errors.Wrap(err, "block: insert utxo failed")
+	}
+
+	if _, err := tx.Exec(`
+		DELETE FROM utxo as u
+		USING unnest($1::varchar[], $2::int[]) as s(hash, vout)
+		WHERE u.hash = s.hash AND u.vout = s.vout`,
+		spentHashes,
+		spentVouts,
+	); err != nil {
+		return errors.Wrap(err, "block: cannot delete spent utxo")
+	}
+
+	b.Stats = CalcStats(b.Transactions, price)
+	if err := insertStats(tx, b.ID, b.Stats); err != nil {
+		return err
+	}
+
+	if b.Miner, err = Attribute(b, pools); err != nil {
+		// block is still valid even if coinbase cannot be parsed
+		log.Printf("block: cannot attribute block %d to pool, %v", b.Height, err)
+		b.Miner = nil
+	} else if err := insertMiner(tx, b.ID, b.Miner); err != nil {
+		return err
+	}
+
+	if b.Reward, err = CalcReward(b); err != nil {
+		return err
+	} else if err := insertReward(tx, b.ID, b.Reward); err != nil {
+		return err
 	}
 	return nil
 }
@@ -103,14 +369,14 @@ func (pg *PGStorage) Insert(b *Block) error {
 // Last10 gets last 10 transactions from database
 func (pg *PGStorage) Last10() ([]Block, error) {
 	blocks := make([]Block, 0)
-	rows, err := pg.con.Query(`SELECT id, bits, height, nonce, version, hash_prev_block, hash_merkle_root, hash, created_at FROM block ORDER BY ID DESC LIMIT 10`)
+	rows, err := pg.con.Query(`SELECT ` + blockColumns + ` ORDER BY b.id DESC LIMIT 10`)
 	if err != nil {
 		return blocks, errors.Wrapf(err, "block: Cannot SELECT FROM BLOCK, %v", err)
 	}
 
 	for rows.Next() {
 		var newB Block
-		if err := rows.Scan(&newB.ID, &newB.Bits, &newB.Height, &newB.Nonce, &newB.Version, &newB.HashPrevBlock, &newB.HashMerkleRoot, &newB.Hash, &newB.CreatedAt); err != nil {
+		if err := scanBlock(rows, &newB); err != nil {
 			return blocks, errors.Wrap(err, "block: Cannot retrieve block database data")
 		}
 		blocks = append(blocks, newB)
@@ -118,12 +384,665 @@ func (pg *PGStorage) Last10() ([]Block, error) {
 	return blocks, nil
 }
 
+// Orphan move all blocks above giving height to stale_block table,
+// delete their transactions and revert address income/outcome/ballance and utxo set.
+// Everything is done in one database transaction
+func (pg *PGStorage) Orphan(height int32) ([]Block, error) {
+	tx, err := pg.con.Begin()
+	if err != nil {
+		return nil, errors.Wrap(err, "block: cannot begin orphan transaction")
+	}
+	defer tx.Rollback()
+
+	stale, err := orphan(tx, height)
+	if err != nil {
+		return stale, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return stale, errors.Wrap(err, "block: cannot commit orphan transaction")
+	}
+	return stale, nil
+}
+
+// InsertStale save block which lost to the current chain to stale_block table
+func (pg *PGStorage) InsertStale(b *Block) error {
+	if _, err := pg.con.Exec(`
+		INSERT INTO stale_block
+			(bits, height, nonce, version, hash_prev_block, hash_merkle_root, created_at, hash)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8)`,
+		b.Bits,
+		b.Height,
+		b.Nonce,
+		b.Version,
+		b.HashPrevBlock,
+		b.HashMerkleRoot,
+		b.CreatedAt,
+		b.Hash,
+	); err != nil {
+		return errors.Wrapf(err, "block: cannot insert stale block %s", b.Hash)
+	}
+	return nil
+}
+
+// orphan move blocks above giving height to stale_block inside database transaction
+func orphan(tx *pgx.Tx, height int32) ([]Block, error) {
+	stale := make([]Block, 0)
+
+	rows, err := tx.Query(`SELECT `+blockColumns+` WHERE b.height > $1 ORDER BY b.height DESC`, height)
+	if err != nil {
+		return stale, errors.Wrap(err, "block: cannot select orphaned blocks")
+	}
+
+	for rows.Next() {
+		var b Block
+		if err := scanBlock(rows, &b); err != nil {
+			rows.Close()
+			return stale, errors.Wrap(err, "block: cannot retrieve orphaned block")
+		}
+		stale = append(stale, b)
+	}
+	rows.Close()
+
+	if len(stale) == 0 {
+		return stale, nil
+	}
+
+	// address_log keep positive amount for income and negative for outcome
+	if _, err := tx.Exec(`
+		UPDATE address as a SET
+			income = a.income - l.income,
+			outcome = a.outcome - l.outcome,
+			ballance = a.ballance - l.income + l.outcome,
+			updated_at = now()
+		FROM (
+			SELECT address_id,
+				sum(greatest(amount, 0)) as income,
+				sum(greatest(-amount, 0)) as outcome
+			FROM address_log
+			WHERE transaction_id IN (
+				SELECT t.id FROM transaction as t JOIN block as b ON b.id = t.block_id
+				WHERE b.height > $1
+			)
+			GROUP BY address_id
+		) as l
+		WHERE a.id = l.address_id`, height,
+	); err != nil {
+		return stale, errors.Wrap(err, "block: cannot revert address ballance")
+	}
+
+	if _, err := tx.Exec(`
+		DELETE FROM address_log WHERE transaction_id IN (
+			SELECT t.id FROM transaction as t JOIN block as b ON b.id = t.block_id
+			WHERE b.height > $1
+		)`, height,
+	); err != nil {
+		return stale, errors.Wrap(err, "block: cannot delete address log")
+	}
+
+	// outputs created below orphan height and spent by orphaned transactions become unspent again
+	if _, err := tx.Exec(`
+		INSERT INTO utxo (hash, vout, address_id, value, height, price)
+		SELECT
+			s.hash,
+			s.vout,
+			coalesce((t.txout->s.vout->>'address_id')::int, 0),
+			(t.txout->s.vout->>'val')::bigint,
+			b.height,
+			coalesce(bs.price, 0)
+		FROM spent_output as s
+			JOIN transaction as t ON t.hash = s.hash
+			JOIN block as b ON b.id = t.block_id
+			LEFT JOIN block_stats as bs ON bs.block_id = b.id
+		WHERE b.height <= $1 AND s.spent_by IN (
+			SELECT st.hash FROM transaction as st JOIN block as sb ON sb.id = st.block_id
+			WHERE sb.height > $1
+		)
+		ON CONFLICT DO NOTHING`, height,
+	); err != nil {
+		return stale, errors.Wrap(err, "block: cannot restore utxo")
+	}
+
+	if _, err := tx.Exec(`DELETE FROM utxo WHERE height > $1`, height); err != nil {
+		return stale, errors.Wrap(err, "block: cannot delete utxo")
+	}
+
+	if _, err := tx.Exec(`
+		DELETE FROM spent_output WHERE spent_by IN (
+			SELECT t.hash FROM transaction as t JOIN block as b ON b.id = t.block_id
+			WHERE b.height > $1
+		)`, height,
+	); err != nil {
+		return stale, errors.Wrap(err, "block: cannot delete spent outputs")
+	}
+
+	if _, err := tx.Exec(`
+		DELETE FROM transaction WHERE block_id IN (
+			SELECT id FROM block WHERE height > $1
+		)`, height,
+	); err != nil {
+		return stale, errors.Wrap(err, "block: cannot delete orphaned transactions")
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO stale_block
+			(block_id, bits, height, nonce, version, hash_prev_block, hash_merkle_root, created_at, hash)
+		SELECT id, bits, height, nonce, version, hash_prev_block, hash_merkle_root, created_at, hash
+		FROM block WHERE height > $1`, height,
+	); err != nil {
+		return stale, errors.Wrap(err, "block: cannot move blocks to stale_block")
+	}
+
+	if _, err := tx.Exec(`DELETE FROM block WHERE height > $1`, height); err != nil {
+		return stale, errors.Wrap(err, "block: cannot delete orphaned blocks")
+	}
+	return stale, nil
+}
+
+// Retargets group blocks by retarget periods
+func (pg *PGStorage) Retargets() ([]Retarget, error) {
+	retargets := make([]Retarget, 0)
+
+	rows, err := pg.con.Query(`
+		SELECT
+			height / $1 as period,
+			min(height),
+			max(height),
+			(array_agg(created_at ORDER BY height ASC))[1],
+			(array_agg(created_at ORDER BY height DESC))[1],
+			count(*),
+			(array_agg(bits ORDER BY height ASC))[1]
+		FROM block
+		GROUP BY period
+		ORDER BY period ASC`, RetargetInterval,
+	)
+	if err != nil {
+		return retargets, errors.Wrap(err, "block: cannot select retarget periods")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r Retarget
+		if err := rows.Scan(
+			&r.Period,
+			&r.StartHeight,
+			&r.EndHeight,
+			&r.StartedAt,
+			&r.EndedAt,
+			&r.Blocks,
+			&r.Bits,
+		); err != nil {
+			return retargets, errors.Wrap(err, "block: cannot retrieve retarget period")
+		}
+		r.calc()
+		retargets = append(retargets, r)
+	}
+	return retargets, rows.Err()
+}
+
+// List return up to limit+1 blocks according to filter
+func (pg *PGStorage) List(f Filter) ([]Block, error) {
+	blocks := make([]Block, 0)
+
+	where := "WHERE true"
+	args := make([]interface{}, 0)
+	order := "DESC"
+
+	if f.Before != nil {
+		args = append(args, *f.Before)
+		where += fmt.Sprintf(" AND b.height < $%d", len(args))
+	}
+
+	if f.After != nil {
+		args = append(args, *f.After)
+		where += fmt.Sprintf(" AND b.height > $%d", len(args))
+		order = "ASC"
+	}
+
+	if !f.From.IsZero() {
+		args = append(args, f.From)
+		where += fmt.Sprintf(" AND b.created_at >= $%d", len(args))
+	}
+
+	if !f.To.IsZero() {
+		args = append(args, f.To)
+		where += fmt.Sprintf(" AND b.created_at < $%d", len(args))
+	}
+
+	args = append(args, f.Limit+1)
+	sql := fmt.Sprintf(`SELECT `+blockColumns+` %s ORDER BY b.height %s LIMIT $%d`, where, order, len(args))
+
+	rows, err := pg.con.Query(sql, args...)
+	if err != nil {
+		return blocks, errors.Wrap(err, "block: cannot list blocks")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var newB Block
+		if err := scanBlock(rows, &newB); err != nil {
+			return blocks, errors.Wrap(err, "block: Cannot retrieve block database data")
+		}
+		blocks = append(blocks, newB)
+	}
+	return blocks, rows.Err()
+}
+
 // getTransactions show transaction
 func (pg *PGStorage) getTransactions(id uint) ([]transaction.Transaction, error) {
 	tranStorage := transaction.NewStorage(pg.con)
 	return transaction.FindTransactions(tranStorage, "block_id", id)
 }
 
+// updateBalances add income/outcome changes to address ballances with one statement
+func updateBalances(db execer, changes []addressChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	ids := make([]int32, 0, len(changes))
+	incomes := make([]int64, 0, len(changes))
+	outcomes := make([]int64, 0, len(changes))
+	for _, c := range changes {
+		ids = append(ids, int32(c.addressID))
+		incomes = append(incomes, c.income)
+		outcomes = append(outcomes, c.outcome)
+	}
+
+	if _, err := db.Exec(`
+		UPDATE address as a SET
+			income = a.income + c.income,
+			outcome = a.outcome + c.outcome,
+			ballance = a.ballance + c.income - c.outcome,
+			updated_at = now()
+		FROM unnest($1::int[], $2::bigint[], $3::bigint[]) as c (id, income, outcome)
+		WHERE a.id = c.id`,
+		ids, incomes, outcomes,
+	); err != nil {
+		return errors.Wrapf(err, "block: cannot update %d addresses", len(changes))
+	}
+	return nil
+}
+
+// NonstandardAddresses return ids of addresses made by old nonstandard scheme
+func (pg *PGStorage) NonstandardAddresses() ([]uint, error) {
+	ids := make([]uint, 0)
+
+	rows, err := pg.con.Query(`
+		SELECT id FROM address
+		WHERE hash LIKE 'nonstandard-%' AND length(hash) <= $1
+		ORDER BY id`, legacyNonstandardLength,
+	)
+	if err != nil {
+		return ids, errors.Wrap(err, "block: cannot select nonstandard addresses")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return ids, errors.Wrap(err, "block: cannot retrieve nonstandard address")
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// RekeyAddress move transactions of legacy nonstandard address to script based
+// addresses, rewrite its address_log, utxo and ballances and delete old address.
+// Everything is done in one database transaction, return amount of changed transactions
+func (pg *PGStorage) RekeyAddress(id uint) (int, error) {
+	tx, err := pg.con.Begin()
+	if err != nil {
+		return 0, errors.Wrap(err, "block: cannot begin rekey transaction")
+	}
+	defer tx.Rollback()
+
+	old := legacyAddress{id: id}
+	if err := tx.QueryRow(`SELECT hash FROM address WHERE id = $1`, id).Scan(&old.hash); err != nil {
+		return 0, errors.Wrapf(err, "block: cannot select address %d", id)
+	}
+
+	// new addresses are created in rekey transaction and rolled back with it
+	addressID := func(hash string) (uint, error) {
+		ids, err := addressIDs(tx, []string{hash})
+		return ids[hash], err
+	}
+
+	rows, err := tx.Query(`
+		SELECT t.id, t.hash, t.txin, t.txout, t.addresses, b.created_at
+		FROM transaction as t JOIN block as b ON b.id = t.block_id
+		WHERE t.addresses @> to_jsonb($1::int)
+		ORDER BY t.id`, id,
+	)
+	if err != nil {
+		return 0, errors.Wrapf(err, "block: cannot select transactions of address %d", id)
+	}
+
+	trans := make([]transaction.Transaction, 0)
+	times := make([]time.Time, 0)
+	for rows.Next() {
+		var t transaction.Transaction
+		var txin, txout []byte
+		var createdAt time.Time
+		if err := rows.Scan(&t.ID, &t.Hash, &txin, &txout, &t.Addresses, &createdAt); err != nil {
+			rows.Close()
+			return 0, errors.Wrapf(err, "block: cannot retrieve transaction of address %d", id)
+		}
+
+		if t.TxIns, err = transaction.DecodeTxIns(txin); err == nil {
+			t.TxOuts, err = transaction.DecodeTxOuts(txout)
+		}
+		if err != nil {
+			rows.Close()
+			return 0, errors.Wrapf(err, "block: cannot decode transaction %s", t.Hash)
+		}
+		trans = append(trans, t)
+		times = append(times, createdAt)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, errors.Wrapf(err, "block: cannot select transactions of address %d", id)
+	}
+
+	logRows := make([][]interface{}, 0)
+	utxoHashes := make([]string, 0)
+	utxoVouts := make([]int32, 0)
+	utxoIDs := make([]int32, 0)
+	for i := range trans {
+		t := &trans[i]
+		tRows, err := rekeyTransaction(pg, addressID, t, old, times[i])
+		if err != nil {
+			return 0, err
+		}
+		logRows = append(logRows, tRows...)
+
+		for vout, out := range t.TxOuts {
+			utxoHashes = append(utxoHashes, t.Hash)
+			utxoVouts = append(utxoVouts, int32(vout))
+			utxoIDs = append(utxoIDs, int32(out.AddressID))
+		}
+
+		txin, err := t.TxInJSONB()
+		if err != nil {
+			return 0, errors.Wrapf(err, "block: cannot encode inputs of %s", t.Hash)
+		}
+		txout, err := t.TxOutJSONB()
+		if err != nil {
+			return 0, errors.Wrapf(err, "block: cannot encode outputs of %s", t.Hash)
+		}
+		addresses, err := t.AddressesJSONB()
+		if err != nil {
+			return 0, errors.Wrapf(err, "block: cannot encode addresses of %s", t.Hash)
+		}
+
+		if _, err := tx.Exec(`
+			UPDATE transaction SET txin = $1, txout = $2, addresses = $3 WHERE id = $4`,
+			txin,
+			txout,
+			addresses,
+			t.ID,
+		); err != nil {
+			return 0, errors.Wrapf(err, "block: cannot update transaction %s", t.Hash)
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM address_log WHERE address_id = $1`, id); err != nil {
+		return 0, errors.Wrapf(err, "block: cannot delete address log of %d", id)
+	}
+
+	if _, err := tx.CopyFrom(
+		pgx.Identifier{"address_log"},
+		[]string{"address_id", "amount", "created_at", "transaction_id"},
+		pgx.CopyFromRows(logRows),
+	); err != nil {
+		return 0, errors.Wrap(err, "block: insert address log failed")
+	}
+
+	if err := updateBalances(tx, logChanges(logRows)); err != nil {
+		return 0, err
+	}
+
+	// only outputs of old address are changed, all of them are rekeyed above
+	if _, err := tx.Exec(`
+		UPDATE utxo as u SET address_id = c.address_id
+		FROM unnest($1::varchar[], $2::int[], $3::int[]) as c (hash, vout, address_id)
+		WHERE u.address_id = $4 AND u.hash = c.hash AND u.vout = c.vout`,
+		utxoHashes, utxoVouts, utxoIDs, id,
+	); err != nil {
+		return 0, errors.Wrapf(err, "block: cannot update utxo of address %d", id)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM address WHERE id = $1`, id); err != nil {
+		return 0, errors.Wrapf(err, "block: cannot delete address %d", id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, errors.Wrap(err, "block: cannot commit rekey transaction")
+	}
+	return len(trans), nil
+}
+
+// SaveStats create or replace block statistics
+func (pg *PGStorage) SaveStats(id uint, s *BlockStats) error {
+	return insertStats(pg.con, id, s)
+}
+
+// insertStats write block statistics using pool or database transaction
+func insertStats(db execer, id uint, s *BlockStats) error {
+	if _, err := db.Exec(`
+		INSERT INTO block_stats
+			(block_id, tx_count, total_in, total_out, total_fee, largest_tx, largest_tx_value,
+			price, total_in_usd, total_out_usd, total_fee_usd, largest_tx_usd)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (block_id) DO UPDATE SET
+			tx_count = EXCLUDED.tx_count,
+			total_in = EXCLUDED.total_in,
+			total_out = EXCLUDED.total_out,
+			total_fee = EXCLUDED.total_fee,
+			largest_tx = EXCLUDED.largest_tx,
+			largest_tx_value = EXCLUDED.largest_tx_value,
+			price = EXCLUDED.price,
+			total_in_usd = EXCLUDED.total_in_usd,
+			total_out_usd = EXCLUDED.total_out_usd,
+			total_fee_usd = EXCLUDED.total_fee_usd,
+			largest_tx_usd = EXCLUDED.largest_tx_usd`,
+		id,
+		s.TxCount,
+		s.TotalIn,
+		s.TotalOut,
+		s.TotalFee,
+		s.LargestTx,
+		s.LargestTxValue,
+		s.Price,
+		s.TotalInUSD,
+		s.TotalOutUSD,
+		s.TotalFeeUSD,
+		s.LargestTxUSD,
+	); err != nil {
+		return errors.Wrapf(err, "block: cannot save stats for block %d", id)
+	}
+	return nil
+}
+
+// getTxOut return output of stored transaction by index
+func (pg *PGStorage) getTxOut(hash string, index uint32) (*transaction.TxOut, error) {
+	var data []byte
+	err := pg.con.QueryRow(`
+		SELECT txout->($2::int)
+		FROM transaction
+		WHERE hash = $1
+		ORDER BY id DESC LIMIT 1`, hash, index,
+	).Scan(&data)
+
+	if err == pgx.ErrNoRows || (err == nil && data == nil) {
+		return nil, ErrNoPrevOut
+	} else if err != nil {
+		return nil, err
+	}
+	return transaction.DecodeTxOut(data)
+}
+
+// addressIDs return ids of address hashes, missing addresses are created
+// with the same database handle, so they are rolled back together with it
+func addressIDs(db queryer, hashes []string) (map[string]uint, error) {
+	ids := make(map[string]uint, len(hashes))
+	if len(hashes) == 0 {
+		return ids, nil
+	}
+
+	if _, err := db.Exec(`
+		INSERT INTO address (hash)
+		SELECT unnest($1::varchar[])
+		ON CONFLICT (hash) DO NOTHING`, hashes,
+	); err != nil {
+		return ids, errors.Wrap(err, "block: cannot create addresses")
+	}
+
+	rows, err := db.Query(`SELECT id, hash FROM address WHERE hash = ANY($1::varchar[])`, hashes)
+	if err != nil {
+		return ids, errors.Wrap(err, "block: cannot select address ids")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uint
+		var hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			return ids, errors.Wrap(err, "block: cannot retrieve address id")
+		}
+		ids[hash] = id
+	}
+	return ids, rows.Err()
+}
+
+// getPools return all pools with identification rules
+func (pg *PGStorage) getPools() ([]Pool, error) {
+	pools := make([]Pool, 0)
+
+	rows, err := pg.con.Query(`SELECT id, name, url, tags, addresses FROM pool ORDER BY id`)
+	if err != nil {
+		return pools, errors.Wrap(err, "block: cannot select pools")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p Pool
+		if err := rows.Scan(&p.ID, &p.Name, &p.URL, &p.Tags, &p.Addresses); err != nil {
+			return pools, errors.Wrap(err, "block: cannot retrieve pool")
+		}
+		pools = append(pools, p)
+	}
+	return pools, rows.Err()
+}
+
+// SaveMiner create or replace block miner
+func (pg *PGStorage) SaveMiner(id uint, m *Miner) error {
+	return insertMiner(pg.con, id, m)
+}
+
+// insertMiner write block miner using pool or database transaction
+func insertMiner(db execer, id uint, m *Miner) error {
+	tags, err := json.Marshal(m.Tags)
+	if err != nil {
+		return errors.Wrap(err, "block: cannot encode miner tags")
+	}
+
+	addresses, err := json.Marshal(m.Addresses)
+	if err != nil {
+		return errors.Wrap(err, "block: cannot encode miner addresses")
+	}
+
+	if _, err := db.Exec(`
+		INSERT INTO block_miner
+			(block_id, pool, height, tags, addresses)
+		VALUES
+			($1, $2, $3, $4, $5)
+		ON CONFLICT (block_id) DO UPDATE SET
+			pool = EXCLUDED.pool,
+			height = EXCLUDED.height,
+			tags = EXCLUDED.tags,
+			addresses = EXCLUDED.addresses`,
+		id,
+		m.Pool,
+		m.Height,
+		string(tags),
+		string(addresses),
+	); err != nil {
+		return errors.Wrapf(err, "block: cannot save miner for block %d", id)
+	}
+	return nil
+}
+
+// SaveReward create or replace block reward
+func (pg *PGStorage) SaveReward(id uint, r *Reward) error {
+	return insertReward(pg.con, id, r)
+}
+
+// insertReward write block reward using pool or database transaction
+func insertReward(db execer, id uint, r *Reward) error {
+	if _, err := db.Exec(`
+		INSERT INTO block_reward
+			(block_id, subsidy, fees, claimed, unclaimed)
+		VALUES
+			($1, $2, $3, $4, $5)
+		ON CONFLICT (block_id) DO UPDATE SET
+			subsidy = EXCLUDED.subsidy,
+			fees = EXCLUDED.fees,
+			claimed = EXCLUDED.claimed,
+			unclaimed = EXCLUDED.unclaimed`,
+		id,
+		r.Subsidy,
+		r.Fees,
+		r.Claimed,
+		r.Unclaimed,
+	); err != nil {
+		return errors.Wrapf(err, "block: cannot save reward for block %d", id)
+	}
+	return nil
+}
+
+// PoolShares count blocks per pool for every period
+func (pg *PGStorage) PoolShares(period string, from time.Time, to time.Time) ([]PoolShare, error) {
+	shares := make([]PoolShare, 0)
+
+	where := "WHERE true"
+	args := []interface{}{period}
+
+	if !from.IsZero() {
+		args = append(args, from)
+		where += fmt.Sprintf(" AND b.created_at >= $%d", len(args))
+	}
+
+	if !to.IsZero() {
+		args = append(args, to)
+		where += fmt.Sprintf(" AND b.created_at < $%d", len(args))
+	}
+
+	rows, err := pg.con.Query(fmt.Sprintf(`
+		SELECT date_trunc($1, b.created_at) as period, coalesce(m.pool, '%s') as pool, count(*)
+		FROM block as b
+		LEFT JOIN block_miner as m ON m.block_id = b.id
+		%s
+		GROUP BY 1, 2
+		ORDER BY 1 ASC, 3 DESC`, UnknownPool, where), args...)
+	if err != nil {
+		return shares, errors.Wrap(err, "block: cannot select pool shares")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s PoolShare
+		if err := rows.Scan(&s.Period, &s.Pool, &s.Blocks); err != nil {
+			return shares, errors.Wrap(err, "block: cannot retrieve pool share")
+		}
+		shares = append(shares, s)
+	}
+	return shares, rows.Err()
+}
+
 // getPricePerBlock return decimal price of bitcoin on the moment when block was created
 func (pg *PGStorage) getPrice(createdAt time.Time) (float32, error) {
 	var price float32
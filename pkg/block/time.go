@@ -0,0 +1,69 @@
+package block
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx"
+)
+
+// TimeMode tells GetByTime which block to take around timestamp
+type TimeMode string
+
+const (
+	// TimeBefore last block created at or before timestamp
+	TimeBefore = TimeMode("before")
+	// TimeAfter first block created at or after timestamp
+	TimeAfter = TimeMode("after")
+	// TimeNearest block with the closest creation time
+	TimeNearest = TimeMode("nearest")
+)
+
+// ErrBadTimeMode error for unknown time mode
+var ErrBadTimeMode = fmt.Errorf("Time mode should be before, after or nearest")
+
+// ParseTimeMode convert string to TimeMode, empty string is nearest
+func ParseTimeMode(mode string) (TimeMode, error) {
+	switch TimeMode(mode) {
+	case "":
+		return TimeNearest, nil
+	case TimeBefore, TimeAfter, TimeNearest:
+		return TimeMode(mode), nil
+	}
+	return "", ErrBadTimeMode
+}
+
+// nearest return block which creation time is closer to t,
+// nil blocks are skipped
+func nearest(t time.Time, before *Block, after *Block) *Block {
+	if before == nil {
+		return after
+	}
+	if after == nil {
+		return before
+	}
+
+	if t.Sub(before.CreatedAt) <= after.CreatedAt.Sub(t) {
+		return before
+	}
+	return after
+}
+
+// HeightRange convert time range to heights of first and last block created in it,
+// it allows to turn time range queries into block height ranges
+func HeightRange(storage Storage, from time.Time, to time.Time) (int32, int32, error) {
+	first, err := storage.GetByTime(from, TimeAfter)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	last, err := storage.GetByTime(to, TimeBefore)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if last.Height < first.Height {
+		return 0, 0, pgx.ErrNoRows
+	}
+	return first.Height, last.Height, nil
+}
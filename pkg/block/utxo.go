@@ -0,0 +1,42 @@
+package block
+
+import (
+	"strings"
+)
+
+// utxoUpsert move staged block outputs to utxo set, output of duplicated txid
+// overwrites the older one like Bitcoin Core does (BIP 30)
+const utxoUpsert = `
+	INSERT INTO utxo (hash, vout, address_id, value, height, price)
+	SELECT hash, vout, address_id, value, height, price FROM utxo_stage
+	ON CONFLICT (hash, vout) DO UPDATE SET
+		address_id = EXCLUDED.address_id,
+		value = EXCLUDED.value,
+		height = EXCLUDED.height,
+		price = EXCLUDED.price`
+
+// utxoChanges return utxo rows for block outputs and outpoints spent by block inputs.
+// Outputs spent in the same block are included in both lists, provably
+// unspendable OP_RETURN outputs never become part of utxo set
+func utxoChanges(b *Block, price float32) ([][]interface{}, []string, []int32) {
+	rows := make([][]interface{}, 0)
+	hashes := make([]string, 0)
+	vouts := make([]int32, 0)
+
+	for _, t := range b.Transactions {
+		if !t.IsCoinbase() {
+			for _, in := range t.TxIns {
+				hashes = append(hashes, in.PrevOut)
+				vouts = append(vouts, int32(in.PrevIndex))
+			}
+		}
+
+		for i, out := range t.TxOuts {
+			if strings.HasPrefix(out.PkScript, "6a") {
+				continue
+			}
+			rows = append(rows, []interface{}{t.Hash, int32(i), out.AddressID, out.Value, b.Height, price})
+		}
+	}
+	return rows, hashes, vouts
+}
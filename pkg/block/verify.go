@@ -0,0 +1,125 @@
+package block
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+	"github.com/webdeveloppro/cryptopiggy/pkg/network"
+	"github.com/webdeveloppro/cryptopiggy/pkg/transaction"
+)
+
+// ErrHashMismatch error for blocks which header hash is different from stored hash
+var ErrHashMismatch = fmt.Errorf("Block header hash mismatch")
+
+// ErrBadTarget error for blocks with negative, zero or above network limit target in bits
+var ErrBadTarget = fmt.Errorf("Block bits target is not valid")
+
+// ErrHighHash error for blocks which hash is above bits target
+var ErrHighHash = fmt.Errorf("Block hash is higher than target")
+
+// ErrMerkleMismatch error for blocks which merkle root do not match transactions
+var ErrMerkleMismatch = fmt.Errorf("Block merkle root mismatch")
+
+// ErrNoTransactions error for blocks without transactions
+var ErrNoTransactions = fmt.Errorf("Block has no transactions")
+
+// Header rebuild 80 bytes block header from stored block fields
+func (b *Block) Header() (*wire.BlockHeader, error) {
+	prev, err := chainhash.NewHashFromStr(b.HashPrevBlock)
+	if err != nil {
+		return nil, errors.Wrapf(err, "block: cannot decode hash_prev_block %s", b.HashPrevBlock)
+	}
+
+	merkle, err := chainhash.NewHashFromStr(b.HashMerkleRoot)
+	if err != nil {
+		return nil, errors.Wrapf(err, "block: cannot decode hash_merkle_root %s", b.HashMerkleRoot)
+	}
+
+	return &wire.BlockHeader{
+		Version:    b.Version,
+		PrevBlock:  *prev,
+		MerkleRoot: *merkle,
+		Timestamp:  b.CreatedAt,
+		Bits:       b.Bits,
+		Nonce:      b.Nonce,
+	}, nil
+}
+
+// MerkleRoot calculate merkle root from transaction hashes,
+// transactions are taken in the order they were inserted
+func MerkleRoot(trans []transaction.Transaction) (chainhash.Hash, error) {
+	if len(trans) == 0 {
+		return chainhash.Hash{}, ErrNoTransactions
+	}
+
+	sorted := make([]transaction.Transaction, len(trans))
+	copy(sorted, trans)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	level := make([]chainhash.Hash, 0, len(sorted))
+	for _, t := range sorted {
+		h, err := chainhash.NewHashFromStr(t.Hash)
+		if err != nil {
+			return chainhash.Hash{}, errors.Wrapf(err, "block: cannot decode transaction hash %s", t.Hash)
+		}
+		level = append(level, *h)
+	}
+
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+
+		next := make([]chainhash.Hash, 0, len(level)/2)
+		for i := 0; i < len(level); i += 2 {
+			var buf [chainhash.HashSize * 2]byte
+			copy(buf[:chainhash.HashSize], level[i][:])
+			copy(buf[chainhash.HashSize:], level[i+1][:])
+			next = append(next, chainhash.DoubleHashH(buf[:]))
+		}
+		level = next
+	}
+
+	return level[0], nil
+}
+
+// Verify check that block header hash equal to stored hash, hash is under
+// bits target allowed on selected network and merkle root match block transactions.
+// Block transactions should be loaded before call
+func Verify(b *Block) error {
+	header, err := b.Header()
+	if err != nil {
+		return err
+	}
+
+	hash := header.BlockHash()
+	if hash.String() != b.Hash {
+		return errors.Wrapf(ErrHashMismatch, "block %d: header hash %s, stored %s", b.Height, hash, b.Hash)
+	}
+
+	target := blockchain.CompactToBig(b.Bits)
+	if target.Sign() <= 0 || target.Cmp(network.Params().PowLimit) > 0 {
+		return errors.Wrapf(ErrBadTarget, "block %d: bits %x", b.Height, b.Bits)
+	}
+
+	if blockchain.HashToBig(&hash).Cmp(target) > 0 {
+		return errors.Wrapf(ErrHighHash, "block %d: hash %s, target %064x", b.Height, hash, target)
+	}
+
+	merkle, err := MerkleRoot(b.Transactions)
+	if err != nil {
+		return errors.Wrapf(err, "block %d", b.Height)
+	}
+
+	if merkle.String() != b.HashMerkleRoot {
+		return errors.Wrapf(ErrMerkleMismatch, "block %d: calculated %s, stored %s", b.Height, merkle, b.HashMerkleRoot)
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package block
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+	"github.com/webdeveloppro/cryptopiggy/pkg/transaction"
+)
+
+// ErrNoPrevOut error for inputs which spend unknown output
+var ErrNoPrevOut = fmt.Errorf("Previous output not found")
+
+// FromWire convert btcd wire block to Block ready for Insert.
+// Input amounts and addresses are resolved from previous outputs,
+// outputs created in the same block are resolved without database.
+// Address ids are assigned by Insert inside its database transaction
+func FromWire(storage Storage, msg *wire.MsgBlock, height int32) (*Block, error) {
+	b := New(storage)
+	b.Bits = msg.Header.Bits
+	b.Height = height
+	b.Nonce = msg.Header.Nonce
+	b.Version = msg.Header.Version
+	b.HashPrevBlock = msg.Header.PrevBlock.String()
+	b.HashMerkleRoot = msg.Header.MerkleRoot.String()
+	b.CreatedAt = msg.Header.Timestamp.UTC()
+	b.Hash = msg.Header.BlockHash().String()
+
+	created := make(map[string][]transaction.TxOut)
+
+	for _, msgTx := range msg.Transactions {
+		t := transaction.FromWire(msgTx)
+
+		if !t.IsCoinbase() {
+			for i, in := range msgTx.TxIn {
+				prevHash := in.PreviousOutPoint.Hash.String()
+				prevIndex := in.PreviousOutPoint.Index
+
+				var out transaction.TxOut
+				if outs, ok := created[prevHash]; ok && int(prevIndex) < len(outs) {
+					out = outs[prevIndex]
+				} else {
+					prev, err := storage.getTxOut(prevHash, prevIndex)
+					if err != nil {
+						return b, errors.Wrapf(err, "block: cannot resolve input %d of %s, %s", i, t.Hash, t.TxIns[i].Outpoint())
+					}
+					out = *prev
+				}
+
+				addrs, err := out.GetAddresses()
+				if err != nil {
+					return b, errors.Wrapf(err, "block: cannot get address for input %d of %s", i, t.Hash)
+				}
+
+				t.TxIns[i].Amount = out.Value
+				t.TxIns[i].ScriptType = pkScriptType(out)
+				if len(addrs) > 0 {
+					t.TxIns[i].Address = addrs[0]
+				}
+			}
+		}
+
+		for i := range t.TxOuts {
+			if _, err := t.TxOuts[i].GetAddresses(); err != nil {
+				return b, errors.Wrapf(err, "block: cannot get addresses for output %d of %s", i, t.Hash)
+			}
+		}
+
+		created[t.Hash] = t.TxOuts
+		b.Transactions = append(b.Transactions, t)
+	}
+
+	return b, nil
+}
+
+// addressHashes return sorted unique addresses of block inputs and outputs
+func addressHashes(b *Block) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, t := range b.Transactions {
+		for _, in := range t.TxIns {
+			if in.Address != "" {
+				seen[in.Address] = true
+			}
+		}
+
+		for i := range t.TxOuts {
+			addrs, err := t.TxOuts[i].GetAddresses()
+			if err != nil {
+				return nil, errors.Wrapf(err, "block: cannot get addresses for output %d of %s", i, t.Hash)
+			}
+			for _, addr := range addrs {
+				seen[addr] = true
+			}
+		}
+	}
+
+	hashes := make([]string, 0, len(seen))
+	for hash := range seen {
+		hashes = append(hashes, hash)
+	}
+	// same order for every insert to avoid deadlocks between parallel inserts
+	sort.Strings(hashes)
+	return hashes, nil
+}
+
+// setAddressIDs fill address ids of inputs and outputs and transaction address lists,
+// multisig output is credited to its first address
+func setAddressIDs(b *Block, ids map[string]uint) {
+	for i := range b.Transactions {
+		t := &b.Transactions[i]
+		seen := make(map[uint]bool)
+		for _, id := range t.Addresses {
+			seen[id] = true
+		}
+
+		add := func(id uint) {
+			if id != 0 && !seen[id] {
+				seen[id] = true
+				t.Addresses = append(t.Addresses, id)
+			}
+		}
+
+		for j := range t.TxIns {
+			in := &t.TxIns[j]
+			if in.AddressID == 0 {
+				in.AddressID = ids[in.Address]
+			}
+			add(in.AddressID)
+		}
+
+		for j := range t.TxOuts {
+			out := &t.TxOuts[j]
+			for k, addr := range out.Addresses {
+				if k == 0 && out.AddressID == 0 {
+					out.AddressID = ids[addr]
+				}
+				add(ids[addr])
+			}
+		}
+	}
+}
+
+// pkScriptType return script type of previous output, outputs of old rows are decoded
+func pkScriptType(out transaction.TxOut) string {
+	if out.ScriptType == "" {
+		out.DecodeScript()
+	}
+	return out.ScriptType
+}
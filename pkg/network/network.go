@@ -0,0 +1,73 @@
+package network
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// ErrUnknownNetwork error for network name which is not supported
+var ErrUnknownNetwork = fmt.Errorf("Unknown network, use mainnet, testnet, signet, regtest or simnet")
+
+// networks maps network names to chain parameters
+var networks = map[string]*chaincfg.Params{
+	"":         &chaincfg.MainNetParams,
+	"mainnet":  &chaincfg.MainNetParams,
+	"testnet":  &chaincfg.TestNet3Params,
+	"testnet3": &chaincfg.TestNet3Params,
+	"signet":   &chaincfg.SigNetParams,
+	"regtest":  &chaincfg.RegressionNetParams,
+	"simnet":   &chaincfg.SimNetParams,
+}
+
+// Activation holds heights from which soft fork script rules are enforced,
+// btcd chain parameters keep them only for BIP34, BIP65 and BIP66
+type Activation struct {
+	BIP16  int32 // P2SH
+	CSV    int32 // BIP68, BIP112 and BIP113
+	Segwit int32 // BIP141 and BIP143
+}
+
+// activations maps chain parameters name to soft fork activation heights.
+// Mainnet P2SH is enforced from 2012-04-01, the first block after it is 173805,
+// so block 170060 which breaks BIP16 rules is not checked with them
+var activations = map[string]Activation{
+	chaincfg.MainNetParams.Name:       {BIP16: 173805, CSV: 419328, Segwit: 481824},
+	chaincfg.TestNet3Params.Name:      {BIP16: 514, CSV: 770112, Segwit: 834624},
+	chaincfg.SigNetParams.Name:        {BIP16: 1, CSV: 1, Segwit: 1},
+	chaincfg.RegressionNetParams.Name: {BIP16: 0, CSV: 1, Segwit: 0},
+	chaincfg.SimNetParams.Name:        {BIP16: 0, CSV: 0, Segwit: 0},
+}
+
+// current chain parameters used for address encoding and validation
+var current = &chaincfg.MainNetParams
+
+// Get return chain parameters by network name, empty name is mainnet
+func Get(name string) (*chaincfg.Params, error) {
+	params, ok := networks[name]
+	if !ok {
+		return nil, ErrUnknownNetwork
+	}
+	return params, nil
+}
+
+// Set select network for transaction, address and block packages,
+// should be called once on startup
+func Set(name string) error {
+	params, err := Get(name)
+	if err != nil {
+		return err
+	}
+	current = params
+	return nil
+}
+
+// Params return selected chain parameters
+func Params() *chaincfg.Params {
+	return current
+}
+
+// Activations return soft fork activation heights of selected network
+func Activations() Activation {
+	return activations[current.Name]
+}
@@ -0,0 +1,48 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+func TestGet(t *testing.T) {
+	if p, err := Get(""); err != nil || p != &chaincfg.MainNetParams {
+		t.Errorf("Get should return mainnet for empty name, got: %v, %v", p, err)
+	}
+
+	if p, err := Get("regtest"); err != nil || p.Name != "regtest" {
+		t.Errorf("Get return wrong regtest params, %v, %v", p, err)
+	}
+
+	if _, err := Get("litecoin"); err != ErrUnknownNetwork {
+		t.Errorf("Get return wrong error, should: %v, got: %v", ErrUnknownNetwork, err)
+	}
+}
+
+func TestSet(t *testing.T) {
+	defer Set("mainnet")
+
+	if err := Set("signet"); err != nil || Params() != &chaincfg.SigNetParams {
+		t.Errorf("Set should select signet, got: %s, %v", Params().Name, err)
+	}
+
+	if err := Set("unknown"); err != ErrUnknownNetwork || Params() != &chaincfg.SigNetParams {
+		t.Errorf("Set should keep previous network on error, got: %s, %v", Params().Name, err)
+	}
+}
+
+func TestActivations(t *testing.T) {
+	defer Set("mainnet")
+
+	if a := Activations(); a.BIP16 != 173805 || a.Segwit != 481824 {
+		t.Errorf("Activations return wrong mainnet heights, %+v", a)
+	}
+
+	for name := range networks {
+		Set(name)
+		if _, ok := activations[Params().Name]; !ok {
+			t.Errorf("Activations has no heights for %s", Params().Name)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package transaction
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ErrNotCoinbase error for parsing coinbase data of regular transaction
+var ErrNotCoinbase = fmt.Errorf("Transaction is not coinbase")
+
+// minTagLength shortest printable run treated as miner tag
+const minTagLength = 4
+
+// Coinbase holds data miner put into coinbase input script
+type Coinbase struct {
+	Height *int32   `json:"height"` // BIP34 height, nil when script do not start with it
+	Tags   []string `json:"tags"`   // printable ASCII found in script
+}
+
+// ParseCoinbase decode coinbase signature script into BIP34 height and miner tags.
+// Script can be stored as hex or as space separated disassembly
+func (t *Transaction) ParseCoinbase() (*Coinbase, error) {
+	if !t.IsCoinbase() || len(t.TxIns) == 0 {
+		return nil, ErrNotCoinbase
+	}
+
+	pushes, err := coinbasePushes(t.TxIns[0].SignatureScript)
+	if err != nil {
+		return nil, err
+	}
+
+	c := Coinbase{Tags: make([]string, 0)}
+	if len(pushes) > 0 && len(pushes[0]) > 0 && len(pushes[0]) <= 4 {
+		var height int32
+		for i := len(pushes[0]) - 1; i >= 0; i-- {
+			height = height<<8 | int32(pushes[0][i])
+		}
+		c.Height = &height
+		pushes = pushes[1:]
+	}
+
+	for _, p := range pushes {
+		c.Tags = append(c.Tags, printableRuns(p)...)
+	}
+	return &c, nil
+}
+
+// coinbasePushes split coinbase script to data pushes,
+// raw script is split by push length prefix until first non push byte
+func coinbasePushes(script string) ([][]byte, error) {
+	pushes := make([][]byte, 0)
+
+	if strings.Contains(script, " ") {
+		for _, token := range strings.Fields(script) {
+			data, err := hex.DecodeString(token)
+			if err != nil {
+				// OP_ codes and other not data tokens
+				continue
+			}
+			pushes = append(pushes, data)
+		}
+		return pushes, nil
+	}
+
+	raw, err := hex.DecodeString(script)
+	if err != nil {
+		return pushes, fmt.Errorf("transaction: cannot decode coinbase script %s, %v", script, err)
+	}
+
+	// first push is BIP34 height for blocks version 2 and above
+	if len(raw) > 0 && raw[0] >= 1 && raw[0] <= 4 && int(raw[0]) < len(raw) {
+		pushes = append(pushes, raw[1:1+raw[0]])
+		raw = raw[1+raw[0]:]
+	} else {
+		pushes = append(pushes, nil)
+	}
+
+	return append(pushes, raw), nil
+}
+
+// printableRuns return ASCII text parts at least minTagLength long
+func printableRuns(data []byte) []string {
+	runs := make([]string, 0)
+	start := -1
+
+	for i := 0; i <= len(data); i++ {
+		printable := i < len(data) && data[i] >= 0x20 && data[i] <= 0x7e
+		if printable && start == -1 {
+			start = i
+		} else if !printable && start != -1 {
+			if i-start >= minTagLength {
+				runs = append(runs, strings.TrimSpace(string(data[start:i])))
+			}
+			start = -1
+		}
+	}
+	return runs
+}
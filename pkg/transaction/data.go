@@ -0,0 +1,119 @@
+package transaction
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// ErrEmptyQuery error for data search without query
+var ErrEmptyQuery = fmt.Errorf("Search query is empty")
+
+// Data search page size limits
+const (
+	DefaultDataLimit = 20
+	MaxDataLimit     = 100
+)
+
+// Data is payload embedded in OP_RETURN output
+type Data struct {
+	Hex      string `json:"hex"`
+	Text     string `json:"text"`     // printable parts of payload
+	Protocol string `json:"protocol"` // empty for unknown protocol
+}
+
+// DataMatch is embedded data found by search
+type DataMatch struct {
+	Data
+	Hash        string `json:"txid"`
+	Vout        uint32 `json:"vout"`
+	BlockHeight int32  `json:"block_height"`
+}
+
+// dataProtocols known payload prefixes
+var dataProtocols = []struct {
+	name   string
+	prefix []byte
+}{
+	{"omni", []byte("omni")},
+	{"counterparty", []byte("CNTRPRTY")},
+	{"rsk", []byte("RSKBLOCK:")},
+	{"veriblock", []byte("VBK")},
+	{"eternitywall", []byte("EW ")},
+	{"witness_commitment", []byte{0xaa, 0x21, 0xa9, 0xed}},
+}
+
+// ParseData return payload pushed after OP_RETURN, nil for other scripts
+func ParseData(script []byte) *Data {
+	if len(script) == 0 || script[0] != txscript.OP_RETURN {
+		return nil
+	}
+
+	pushes, err := txscript.PushedData(script[1:])
+	if err != nil {
+		// keep unparsable tail as is
+		pushes = [][]byte{script[1:]}
+	}
+	payload := bytes.Join(pushes, nil)
+
+	d := Data{
+		Hex:      hex.EncodeToString(payload),
+		Text:     strings.Join(printableRuns(payload), " "),
+		Protocol: dataProtocol(payload),
+	}
+	return &d
+}
+
+// dataProtocol detect protocol by payload prefix
+func dataProtocol(payload []byte) string {
+	for _, p := range dataProtocols {
+		if bytes.HasPrefix(payload, p.prefix) {
+			return p.name
+		}
+	}
+
+	// OpenTimestamps calendars and similar services commit bare 32 byte hash
+	if len(payload) == 32 {
+		return "hash_commitment"
+	}
+	return ""
+}
+
+// dataRows return tx_data rows for OP_RETURN outputs of transactions
+func dataRows(trans []Transaction) [][]interface{} {
+	rows := make([][]interface{}, 0)
+	for _, t := range trans {
+		for i, out := range t.TxOuts {
+			if out.Data == nil {
+				continue
+			}
+			rows = append(rows, []interface{}{t.ID, uint32(i), out.Data.Hex, out.Data.Text, out.Data.Protocol})
+		}
+	}
+	return rows
+}
+
+// SearchData find OP_RETURN payloads which contain hex or text query
+func SearchData(reader Storage, q string, limit int) ([]DataMatch, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return nil, ErrEmptyQuery
+	}
+
+	if limit <= 0 {
+		limit = DefaultDataLimit
+	} else if limit > MaxDataLimit {
+		limit = MaxDataLimit
+	}
+
+	// hex query also matches text, "cafe" is valid hex and word
+	hexQuery := ""
+	if _, err := hex.DecodeString(q); err == nil {
+		hexQuery = strings.ToLower(q)
+	}
+
+	return reader.SearchData(hexQuery, q, limit)
+}
@@ -0,0 +1,137 @@
+package transaction
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// ErrBadRawTx error for raw transaction which is not valid hex or cannot be deserialized
+var ErrBadRawTx = fmt.Errorf("Wrong raw transaction")
+
+// Previous output statuses of decoded transaction inputs
+const (
+	InputUnspent = "unspent"
+	InputSpent   = "spent" // spent by transaction in database
+)
+
+// DecodedInput is previous output status of raw transaction input,
+// SpentBy with the decoded transaction hash means it is already stored
+type DecodedInput struct {
+	Index    int      `json:"index"`
+	Outpoint string   `json:"outpoint"`
+	Status   string   `json:"status"`
+	SpentBy  *SpentBy `json:"spent_by,omitempty"`
+}
+
+// Decoded is raw transaction enriched with previous outputs from database,
+// fee is known only when every input is resolved
+type Decoded struct {
+	Transaction Transaction    `json:"transaction"`
+	Inputs      []DecodedInput `json:"inputs"`
+	Complete    bool           `json:"complete"`
+	ValueUSD    float64        `json:"value_usd"`
+	FeeUSD      float64        `json:"fee_usd"`
+}
+
+// Decode deserialize raw transaction hex, resolve input values and addresses
+// from stored previous outputs and calculate fee and USD value at the last price
+func Decode(reader Storage, raw string) (*Decoded, error) {
+	data, err := hex.DecodeString(strings.TrimSpace(raw))
+	if err != nil || len(data) == 0 {
+		return nil, ErrBadRawTx
+	}
+
+	msg, err := deserialize(data)
+	if err != nil {
+		return nil, err
+	}
+
+	price, err := reader.GetLastPrice()
+	if err != nil {
+		return nil, err
+	}
+
+	load := func(hash string) (*Transaction, error) {
+		t, err := FindTransaction(reader, "hash", hash)
+		if err == ErrNoTran {
+			return nil, nil
+		}
+		return &t, err
+	}
+	return decode(load, FromWire(msg), price)
+}
+
+// deserialize parse raw transaction, data left after transaction is not accepted
+func deserialize(data []byte) (*wire.MsgTx, error) {
+	r := bytes.NewReader(data)
+	msg := wire.NewMsgTx(wire.TxVersion)
+	if err := msg.Deserialize(r); err != nil || r.Len() != 0 {
+		return nil, ErrBadRawTx
+	}
+	return msg, nil
+}
+
+// decode resolve inputs with loader function, loader return nil for unknown transaction
+func decode(load func(string) (*Transaction, error), t Transaction, price float32) (*Decoded, error) {
+	d := &Decoded{
+		Inputs:   make([]DecodedInput, 0, len(t.TxIns)),
+		Complete: true,
+	}
+
+	if !t.IsCoinbase() {
+		prevs := make(map[string]*Transaction)
+		for i := range t.TxIns {
+			in := &t.TxIns[i]
+			prev, ok := prevs[in.PrevOut]
+			if !ok {
+				var err error
+				if prev, err = load(in.PrevOut); err != nil {
+					return nil, err
+				}
+				prevs[in.PrevOut] = prev
+			}
+
+			status := DecodedInput{Index: i, Outpoint: in.Outpoint(), Status: InputUnknown}
+			if prev != nil && int(in.PrevIndex) < len(prev.TxOuts) {
+				out := prev.TxOuts[in.PrevIndex]
+				addrs, err := out.GetAddresses()
+				if err != nil {
+					return nil, err
+				}
+
+				in.Amount = out.Value
+				in.AddressID = out.AddressID
+				if out.ScriptType != "" {
+					in.ScriptType = out.ScriptType
+				}
+				if len(addrs) > 0 {
+					in.Address = addrs[0]
+				}
+
+				status.Status = InputUnspent
+				if out.SpentBy != nil {
+					status.Status = InputSpent
+					status.SpentBy = out.SpentBy
+				}
+			} else {
+				d.Complete = false
+			}
+			d.Inputs = append(d.Inputs, status)
+		}
+	}
+
+	t.CalcFee()
+	if !d.Complete {
+		t.Fee, t.FeeRate = 0, 0
+	}
+
+	t.Price = price
+	d.ValueUSD = float64(t.OutputValue()) / 1e8 * float64(price)
+	d.FeeUSD = float64(t.Fee) / 1e8 * float64(price)
+	d.Transaction = t
+	return d, nil
+}
@@ -0,0 +1,64 @@
+package transaction
+
+import (
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// CalcFee fill fee, byte size, weight, virtual size, witness discount and fee rate in sat/vbyte,
+// size and weight are rebuilt from stored inputs and outputs if they are not known yet
+func (t *Transaction) CalcFee() {
+	if t.Size == 0 || t.Weight == 0 {
+		t.Size, t.Weight = t.estimateSize()
+	}
+	t.VSize = vsize(t.Weight)
+	t.WitnessDiscount = t.Size - t.VSize
+
+	t.Fee = 0
+	if !t.IsCoinbase() {
+		t.Fee = t.InputValue() - t.OutputValue()
+	}
+
+	t.FeeRate = 0
+	if t.VSize > 0 {
+		t.FeeRate = float64(t.Fee) / float64(t.VSize)
+	}
+}
+
+// estimateSize rebuild serialized size and weight from stored fields
+func (t *Transaction) estimateSize() (int, int) {
+	// version and lock time
+	base := 8
+	base += wire.VarIntSerializeSize(uint64(len(t.TxIns)))
+	for _, in := range t.TxIns {
+		base += in.Size
+	}
+
+	base += wire.VarIntSerializeSize(uint64(len(t.TxOuts)))
+	for _, out := range t.TxOuts {
+		scriptLen := len(out.PkScript) / 2
+		base += 8 + wire.VarIntSerializeSize(uint64(scriptLen)) + scriptLen
+	}
+
+	witnesses := false
+	for _, in := range t.TxIns {
+		witnesses = witnesses || in.Witness != ""
+	}
+
+	if !t.HasWitness && !witnesses {
+		return base, base * blockchain.WitnessScaleFactor
+	}
+
+	// marker and flag bytes
+	witness := 2
+	for _, in := range t.TxIns {
+		items := splitWitness(in.Witness)
+		witness += wire.VarIntSerializeSize(uint64(len(items)))
+		for _, item := range items {
+			itemLen := len(item) / 2
+			witness += wire.VarIntSerializeSize(uint64(itemLen)) + itemLen
+		}
+	}
+
+	return base + witness, base*blockchain.WitnessScaleFactor + witness
+}
@@ -0,0 +1,192 @@
+package transaction
+
+import (
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// JSONBVersion is schema version of txin/txout jsonb items.
+// Items written by btcd2sql and older versions of this package have no "v" field,
+// they are version 1 and are upgraded on read
+const JSONBVersion = 2
+
+// txInRecord is txin jsonb item schema
+type txInRecord struct {
+	V               int    `json:"v"`
+	Amount          int64  `json:"amount"`
+	AddressID       uint   `json:"address_id"`
+	Address         string `json:"address"`
+	PrevOut         string `json:"prev_out"`
+	PrevIndex       uint32 `json:"prev_index"`
+	Size            int    `json:"size"`
+	SignatureScript string `json:"signature_script"`
+	ScriptHex       string `json:"script_hex,omitempty"` // raw signature script, empty in older items
+	Sequence        uint32 `json:"sequence"`
+	Witness         string `json:"witness"`
+	ScriptType      string `json:"script_type,omitempty"` // type of spent output, empty in older items
+}
+
+// txOutRecord is txout jsonb item schema
+type txOutRecord struct {
+	V          int      `json:"v"`
+	Value      int64    `json:"val"`
+	PkScript   string   `json:"pk_script"`
+	AddressID  uint     `json:"address_id"`
+	Addresses  []string `json:"addresses"`
+	ScriptType string   `json:"script_type"`
+}
+
+// TxInJSONB transform TxIn array for pg jsonb insert
+func (t *Transaction) TxInJSONB() (string, error) {
+	records := make([]txInRecord, 0, len(t.TxIns))
+	for _, in := range t.TxIns {
+		records = append(records, txInRecord{
+			V:               JSONBVersion,
+			Amount:          in.Amount,
+			AddressID:       in.AddressID,
+			Address:         in.Address,
+			PrevOut:         in.PrevOut,
+			PrevIndex:       in.PrevIndex,
+			Size:            in.Size,
+			SignatureScript: in.SignatureScript,
+			ScriptHex:       in.ScriptHex,
+			Sequence:        in.Sequence,
+			Witness:         in.Witness,
+			ScriptType:      in.ScriptType,
+		})
+	}
+	return marshalJSONB(records)
+}
+
+// TxOutJSONB transform TxOut array for pg jsonb insert
+func (t *Transaction) TxOutJSONB() (string, error) {
+	records := make([]txOutRecord, 0, len(t.TxOuts))
+	for _, out := range t.TxOuts {
+		// output which script cannot be decoded is stored without addresses
+		addrs, err := out.GetAddresses()
+		if err != nil {
+			addrs = []string{}
+		}
+
+		records = append(records, txOutRecord{
+			V:          JSONBVersion,
+			Value:      out.Value,
+			PkScript:   out.PkScript,
+			AddressID:  out.AddressID,
+			Addresses:  addrs,
+			ScriptType: pkScriptType(out.PkScript),
+		})
+	}
+	return marshalJSONB(records)
+}
+
+// AddressesJSONB transform Addresses array for pg jsonb insert
+func (t *Transaction) AddressesJSONB() (string, error) {
+	if t.Addresses == nil {
+		return "[]", nil
+	}
+	return marshalJSONB(t.Addresses)
+}
+
+// marshalJSONB encode value for pg jsonb insert
+func marshalJSONB(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", errors.Wrap(err, "transaction: cannot encode jsonb")
+	}
+	return string(data), nil
+}
+
+// jsonbColumns return txin, txout and addresses jsonb values of transaction
+func (t *Transaction) jsonbColumns() (string, string, string, error) {
+	txin, err := t.TxInJSONB()
+	if err != nil {
+		return "", "", "", err
+	}
+	txout, err := t.TxOutJSONB()
+	if err != nil {
+		return "", "", "", err
+	}
+	addresses, err := t.AddressesJSONB()
+	return txin, txout, addresses, err
+}
+
+// DecodeTxIns decode txin jsonb column, older items are upgraded to current schema
+func DecodeTxIns(data []byte) ([]TxIn, error) {
+	records := make([]txInRecord, 0)
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, errors.Wrap(err, "transaction: cannot decode txin")
+	}
+
+	ins := make([]TxIn, 0, len(records))
+	for _, r := range records {
+		ins = append(ins, TxIn{
+			Amount:          r.Amount,
+			AddressID:       r.AddressID,
+			Address:         r.Address,
+			PrevOut:         r.PrevOut,
+			PrevIndex:       r.PrevIndex,
+			Size:            r.Size,
+			SignatureScript: r.SignatureScript,
+			ScriptHex:       r.ScriptHex,
+			Sequence:        r.Sequence,
+			Witness:         r.Witness,
+			ScriptType:      r.ScriptType,
+		})
+	}
+	return ins, nil
+}
+
+// DecodeTxOuts decode txout jsonb column, older items are upgraded to current schema
+func DecodeTxOuts(data []byte) ([]TxOut, error) {
+	records := make([]txOutRecord, 0)
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, errors.Wrap(err, "transaction: cannot decode txout")
+	}
+
+	outs := make([]TxOut, 0, len(records))
+	for _, r := range records {
+		outs = append(outs, r.upgrade())
+	}
+	return outs, nil
+}
+
+// DecodeTxOut decode one txout jsonb item
+func DecodeTxOut(data []byte) (*TxOut, error) {
+	var r txOutRecord
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, errors.Wrap(err, "transaction: cannot decode txout")
+	}
+
+	out := r.upgrade()
+	return &out, nil
+}
+
+// upgrade convert record of any version to TxOut
+func (r txOutRecord) upgrade() TxOut {
+	out := TxOut{
+		Value:      r.Value,
+		PkScript:   r.PkScript,
+		AddressID:  r.AddressID,
+		Addresses:  r.Addresses,
+		ScriptType: r.ScriptType,
+	}
+
+	if r.V < 2 {
+		// version 1 has no addresses and script type, addresses are decoded on demand
+		out.Addresses = nil
+		out.ScriptType = pkScriptType(r.PkScript)
+	}
+	return out
+}
+
+// pkScriptType return script type of hex output script
+func pkScriptType(pkScript string) string {
+	script, err := hex.DecodeString(pkScript)
+	if err != nil {
+		return ScriptNonStandard
+	}
+	return ScriptType(script)
+}
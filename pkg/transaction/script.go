@@ -0,0 +1,225 @@
+package transaction
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// Output script types
+const (
+	ScriptP2PK        = "p2pk"
+	ScriptP2PKH       = "p2pkh"
+	ScriptP2SH        = "p2sh"
+	ScriptMultisig    = "multisig"
+	ScriptP2WPKH      = "p2wpkh"
+	ScriptP2WSH       = "p2wsh"
+	ScriptP2TR        = "p2tr"
+	ScriptOpReturn    = "op_return"
+	ScriptNonStandard = "nonstandard"
+	ScriptCoinbase    = "coinbase" // input type only, coinbase spends nothing
+)
+
+// scriptTypes maps btcd script classes to our script types
+var scriptTypes = map[txscript.ScriptClass]string{
+	txscript.PubKeyTy:              ScriptP2PK,
+	txscript.PubKeyHashTy:          ScriptP2PKH,
+	txscript.ScriptHashTy:          ScriptP2SH,
+	txscript.MultiSigTy:            ScriptMultisig,
+	txscript.WitnessV0PubKeyHashTy: ScriptP2WPKH,
+	txscript.WitnessV0ScriptHashTy: ScriptP2WSH,
+	txscript.NullDataTy:            ScriptOpReturn,
+}
+
+// ScriptType return type of output script
+func ScriptType(script []byte) string {
+	// btcd v0.22 does not know taproot, witness v1 program is OP_1 followed by 32 bytes push
+	if len(script) == 34 && script[0] == txscript.OP_1 && script[1] == txscript.OP_DATA_32 {
+		return ScriptP2TR
+	}
+
+	// OP_RETURN with data larger than standard relay limit is still OP_RETURN
+	if len(script) > 0 && script[0] == txscript.OP_RETURN {
+		return ScriptOpReturn
+	}
+
+	if typ, ok := scriptTypes[txscript.GetScriptClass(script)]; ok {
+		return typ
+	}
+	return ScriptNonStandard
+}
+
+// ValidScriptType return true for known output script type
+func ValidScriptType(typ string) bool {
+	switch typ {
+	case ScriptP2PK, ScriptP2PKH, ScriptP2SH, ScriptMultisig, ScriptP2WPKH,
+		ScriptP2WSH, ScriptP2TR, ScriptOpReturn, ScriptNonStandard:
+		return true
+	}
+	return false
+}
+
+// DecodeScript fill script type and disassembly of output script
+func (txOut *TxOut) DecodeScript() {
+	script, err := hex.DecodeString(txOut.PkScript)
+	if err != nil {
+		txOut.ScriptType = ScriptNonStandard
+		txOut.Asm = txOut.PkScript
+		return
+	}
+
+	txOut.ScriptType = ScriptType(script)
+	txOut.Asm = disasmString(script)
+	txOut.Data = ParseData(script)
+}
+
+// DecodeScript fill script type, disassembly of input script, witness items, public key and signature.
+// Coinbase script is kept in hex, other input scripts are already disassembled.
+// Script type is the type of spent output, it is set on import from previous output
+// and guessed from script and witness for older rows
+func (txIn *TxIn) DecodeScript(coinbase bool) {
+	txIn.Asm = txIn.SignatureScript
+	txIn.WitnessItems = splitWitness(txIn.Witness)
+	if coinbase {
+		txIn.ScriptType = ScriptCoinbase
+		if script, err := hex.DecodeString(txIn.SignatureScript); err == nil {
+			txIn.Asm = disasmString(script)
+		}
+		return
+	}
+
+	if txIn.ScriptType == "" {
+		txIn.ScriptType = inputScriptType(strings.Fields(txIn.Asm), txIn.WitnessItems)
+	}
+
+	// segwit inputs keep signature and public key in witness
+	items := append(strings.Fields(txIn.Asm), txIn.WitnessItems...)
+	for _, item := range items {
+		data, err := hex.DecodeString(item)
+		if err != nil {
+			continue
+		}
+
+		if txIn.Signature == "" && isSignature(data) {
+			txIn.Signature = item
+		} else if txIn.PubKey == "" && isPubKey(data) {
+			txIn.PubKey = item
+		}
+	}
+
+	// taproot key path spend has only schnorr signature in witness
+	if txIn.Signature == "" && txIn.SignatureScript == "" {
+		witness := txIn.WitnessItems
+		if len(witness) == 1 && (len(witness[0]) == 128 || len(witness[0]) == 130) {
+			txIn.Signature = witness[0]
+		}
+	}
+}
+
+// inputScriptType guess type of spent output by spend pattern of input script and witness
+func inputScriptType(asm []string, witness []string) string {
+	if len(asm) == 0 {
+		// annex is optional last item of taproot witness
+		if len(witness) > 1 && strings.HasPrefix(witness[len(witness)-1], "50") {
+			witness = witness[:len(witness)-1]
+		}
+
+		switch {
+		case len(witness) == 0:
+			return ScriptNonStandard
+		case len(witness) == 2 && isPubKeyHex(witness[1]):
+			return ScriptP2WPKH
+		case len(witness) == 1 && (len(witness[0]) == 128 || len(witness[0]) == 130):
+			return ScriptP2TR
+		case len(witness) > 1 && isControlBlock(witness[len(witness)-1]):
+			return ScriptP2TR
+		}
+		return ScriptP2WSH
+	}
+
+	// nested segwit pushes witness program as p2sh redeem script
+	if len(witness) > 0 {
+		return ScriptP2SH
+	}
+
+	items := make([][]byte, 0, len(asm))
+	for _, item := range asm {
+		if item == "0" {
+			items = append(items, nil)
+			continue
+		}
+
+		data, err := hex.DecodeString(item)
+		if err != nil {
+			// opcodes in input script are not standard
+			return ScriptNonStandard
+		}
+		items = append(items, data)
+	}
+
+	last := items[len(items)-1]
+	switch {
+	case len(items) == 1 && isSignature(last):
+		return ScriptP2PK
+	case len(items) == 2 && isSignature(items[0]) && isPubKey(last):
+		return ScriptP2PKH
+	case len(items) > 1 && items[0] == nil && allSignatures(items[1:]):
+		return ScriptMultisig
+	case len(last) > 0 && !isSignature(last) && !isPubKey(last):
+		if _, err := txscript.DisasmString(last); err == nil {
+			return ScriptP2SH
+		}
+	}
+	return ScriptNonStandard
+}
+
+// allSignatures check all items are signatures
+func allSignatures(items [][]byte) bool {
+	for _, item := range items {
+		if !isSignature(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// isPubKeyHex check hex item is public key
+func isPubKeyHex(item string) bool {
+	data, err := hex.DecodeString(item)
+	return err == nil && isPubKey(data)
+}
+
+// isControlBlock check hex item is taproot control block, 33 + 32*m bytes with leaf version
+func isControlBlock(item string) bool {
+	data, err := hex.DecodeString(item)
+	return err == nil && len(data) >= 33 && (len(data)-33)%32 == 0 && data[0]&0xfe == 0xc0
+}
+
+// DecodeScripts fill script data for all inputs and outputs
+func (t *Transaction) DecodeScripts() {
+	coinbase := t.IsCoinbase()
+	for i := range t.TxIns {
+		t.TxIns[i].DecodeScript(coinbase)
+	}
+
+	for i := range t.TxOuts {
+		t.TxOuts[i].DecodeScript()
+	}
+}
+
+// isSignature check data is DER encoded signature followed by sighash type byte
+func isSignature(data []byte) bool {
+	return len(data) >= 9 && len(data) <= 73 && data[0] == 0x30 && int(data[1]) == len(data)-3
+}
+
+// isPubKey check data is compressed or uncompressed public key
+func isPubKey(data []byte) bool {
+	switch len(data) {
+	case 33:
+		return data[0] == 0x02 || data[0] == 0x03
+	case 65:
+		return data[0] == 0x04
+	}
+	return false
+}
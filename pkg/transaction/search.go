@@ -0,0 +1,129 @@
+package transaction
+
+import (
+	"fmt"
+	"time"
+)
+
+// Search page size limits
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// ErrBadSort error for unknown sort field or order
+var ErrBadSort = fmt.Errorf("Wrong sort, use height, value, fee or fee_rate and asc or desc order")
+
+// ErrBadScriptType error for unknown script type filter
+var ErrBadScriptType = fmt.Errorf("Wrong script type")
+
+// ErrBadRange error for filter range where minimum is bigger than maximum
+var ErrBadRange = fmt.Errorf("Wrong range, minimum is bigger than maximum")
+
+// sortColumns maps sort parameter to transaction columns, t.id breaks ties
+var sortColumns = map[string]string{
+	"height":   "b.height",
+	"value":    "t.value",
+	"fee":      "t.fee",
+	"fee_rate": "t.fee_rate",
+}
+
+// scriptPatterns match version 1 txout items without script_type by pk_script,
+// p2pk, multisig and nonstandard outputs are found only in newer items
+var scriptPatterns = map[string]string{
+	ScriptP2PKH:    "76a914________________________________________88ac",
+	ScriptP2SH:     "a914________________________________________87",
+	ScriptP2WPKH:   "0014________________________________________",
+	ScriptP2WSH:    "0020________________________________________________________________",
+	ScriptP2TR:     "5120________________________________________________________________",
+	ScriptOpReturn: "6a%",
+}
+
+// SearchFilter holds transaction search parameters, nil and zero values are ignored
+type SearchFilter struct {
+	FromHeight *int32
+	ToHeight   *int32
+	From       time.Time // block created at or after from
+	To         time.Time // block created before to
+	MinValue   *int64    // total output value
+	MaxValue   *int64
+	MinFee     *int64
+	MaxFee     *int64
+	Address    string // address hash which is input or output of transaction
+	ScriptType string // any output has script type
+	MinInputs  *int
+	MaxInputs  *int
+	MinOutputs *int
+	MaxOutputs *int
+	Sort       string // height (default), value, fee or fee_rate
+	Order      string // desc (default) or asc
+	Limit      int
+	Offset     int
+}
+
+// SearchPage holds one page of transactions and offset of the next page
+type SearchPage struct {
+	Transactions []Transaction `json:"transactions"`
+	NextOffset   *int          `json:"next_offset"`
+}
+
+// Search return page of transactions according to filter
+func Search(reader Storage, f SearchFilter) (SearchPage, error) {
+	page := SearchPage{Transactions: make([]Transaction, 0)}
+
+	if f.Sort == "" {
+		f.Sort = "height"
+	}
+	if f.Order == "" {
+		f.Order = "desc"
+	}
+	if _, ok := sortColumns[f.Sort]; !ok || (f.Order != "asc" && f.Order != "desc") {
+		return page, ErrBadSort
+	}
+
+	if f.ScriptType != "" && !ValidScriptType(f.ScriptType) {
+		return page, ErrBadScriptType
+	}
+
+	if badHeightRange(f.FromHeight, f.ToHeight) || badRange(f.MinValue, f.MaxValue) ||
+		badRange(f.MinFee, f.MaxFee) || badCountRange(f.MinInputs, f.MaxInputs) ||
+		badCountRange(f.MinOutputs, f.MaxOutputs) || (!f.From.IsZero() && !f.To.IsZero() && f.From.After(f.To)) {
+		return page, ErrBadRange
+	}
+
+	if f.Limit <= 0 {
+		f.Limit = DefaultSearchLimit
+	} else if f.Limit > MaxSearchLimit {
+		f.Limit = MaxSearchLimit
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+
+	trans, err := reader.Search(f)
+	if err != nil {
+		return page, err
+	}
+
+	// storage return one extra transaction to show that next page exists
+	if len(trans) > f.Limit {
+		trans = trans[:f.Limit]
+		next := f.Offset + f.Limit
+		page.NextOffset = &next
+	}
+
+	page.Transactions = trans
+	return page, nil
+}
+
+func badRange(min, max *int64) bool {
+	return min != nil && max != nil && *min > *max
+}
+
+func badHeightRange(min, max *int32) bool {
+	return min != nil && max != nil && *min > *max
+}
+
+func badCountRange(min, max *int) bool {
+	return min != nil && max != nil && *min > *max
+}
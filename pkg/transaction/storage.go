@@ -1,7 +1,9 @@
 package transaction
 
 import (
+	"fmt"
 	"log"
+	"strings"
 
 	"github.com/jackc/pgx"
 	"github.com/pkg/errors"
@@ -12,6 +14,10 @@ type Storage interface {
 	Insert(*Transaction) error
 	GetByWhere(string, ...interface{}) ([]Transaction, error)
 	GetPricePerTransaction([]Transaction) error
+	GetLastPrice() (float32, error)
+	GetBlockInfo(*Transaction) error
+	SearchData(string, string, int) ([]DataMatch, error)
+	Search(SearchFilter) ([]Transaction, error)
 }
 
 // PGStorage for application working on postgresql database
@@ -28,9 +34,12 @@ func NewStorage(con *pgx.ConnPool) *PGStorage {
 
 // Insert transaction to the database
 func (pg *PGStorage) Insert(t *Transaction) error {
+	t.CalcFee()
+	t.DecodeScripts()
+
 	sql := `
 			INSERT INTO transaction
-				(hash, block_id, has_witness, txin, txout, addresses)
+				(hash, block_id, has_witness, txin, txout, addresses, fee, size, vsize, fee_rate, value, weight, witness_discount, version, lock_time)
 			VALUES
 				(
 					$1,
@@ -38,17 +47,40 @@ func (pg *PGStorage) Insert(t *Transaction) error {
 					$3,
 					$4,
 					$5,
-					$6
+					$6,
+					$7,
+					$8,
+					$9,
+					$10,
+					$11,
+					$12,
+					$13,
+					$14,
+					$15
 				)
 				RETURNING id`
 
-	err := pg.con.QueryRow(sql,
+	txin, txout, addresses, err := t.jsonbColumns()
+	if err != nil {
+		return errors.Wrapf(err, "transaction: cannot encode %s", t.Hash)
+	}
+
+	err = pg.con.QueryRow(sql,
 		t.Hash,
 		t.BlockID,
 		t.HasWitness,
-		t.TxInJSONB(),
-		t.TxOutJSONB(),
-		t.AddressesJSONB(),
+		txin,
+		txout,
+		addresses,
+		t.Fee,
+		t.Size,
+		t.VSize,
+		t.FeeRate,
+		t.OutputValue(),
+		t.Weight,
+		t.WitnessDiscount,
+		t.Version,
+		int64(t.LockTime),
 	).Scan(&t.ID)
 
 	if err != nil {
@@ -60,9 +92,163 @@ func (pg *PGStorage) Insert(t *Transaction) error {
 
 		return errors.Wrap(err, "insert transaction failed")
 	}
+
+	for _, row := range dataRows([]Transaction{*t}) {
+		if _, err := pg.con.Exec(`
+			INSERT INTO tx_data (transaction_id, vout, hex, text, protocol)
+			VALUES ($1, $2, $3, $4, $5)`, row...,
+		); err != nil {
+			return errors.Wrapf(err, "transaction: cannot insert data for %s", t.Hash)
+		}
+	}
+
+	for _, row := range spentRows([]Transaction{*t}) {
+		if _, err := pg.con.Exec(`
+			INSERT INTO spent_output (hash, vout, spent_by, vin)
+			VALUES ($1, $2, $3, $4)`, row...,
+		); err != nil {
+			return errors.Wrapf(err, "transaction: cannot insert spent output for %s", t.Hash)
+		}
+	}
 	return nil
 }
 
+// CopyFrom bulk insert transactions with COPY protocol inside database transaction,
+// ids are reserved from transaction sequence before copy and filled to every transaction
+func CopyFrom(tx *pgx.Tx, trans []Transaction) error {
+	if len(trans) == 0 {
+		return nil
+	}
+
+	rows, err := tx.Query(`SELECT nextval('transaction_id_seq') FROM generate_series(1, $1)`, len(trans))
+	if err != nil {
+		return errors.Wrap(err, "transaction: cannot reserve ids")
+	}
+
+	i := 0
+	for rows.Next() {
+		if err := rows.Scan(&trans[i].ID); err != nil {
+			rows.Close()
+			return errors.Wrap(err, "transaction: cannot read reserved id")
+		}
+		i++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return errors.Wrap(err, "transaction: cannot reserve ids")
+	}
+
+	copyRows := make([][]interface{}, 0, len(trans))
+	for i := range trans {
+		t := &trans[i]
+		t.CalcFee()
+		t.DecodeScripts()
+		txin, txout, addresses, err := t.jsonbColumns()
+		if err != nil {
+			return errors.Wrapf(err, "transaction: cannot encode %s", t.Hash)
+		}
+
+		copyRows = append(copyRows, []interface{}{
+			t.ID,
+			t.Hash,
+			t.BlockID,
+			t.HasWitness,
+			txin,
+			txout,
+			addresses,
+			t.Fee,
+			t.Size,
+			t.VSize,
+			t.FeeRate,
+			t.OutputValue(),
+			t.Weight,
+			t.WitnessDiscount,
+			t.Version,
+			int64(t.LockTime),
+		})
+	}
+
+	if _, err := tx.CopyFrom(
+		pgx.Identifier{"transaction"},
+		[]string{"id", "hash", "block_id", "has_witness", "txin", "txout", "addresses", "fee", "size", "vsize", "fee_rate", "value", "weight", "witness_discount", "version", "lock_time"},
+		pgx.CopyFromRows(copyRows),
+	); err != nil {
+		return errors.Wrap(err, "transaction: copy failed")
+	}
+
+	if _, err := tx.CopyFrom(
+		pgx.Identifier{"spent_output"},
+		[]string{"hash", "vout", "spent_by", "vin"},
+		pgx.CopyFromRows(spentRows(trans)),
+	); err != nil {
+		return errors.Wrap(err, "transaction: spent output copy failed")
+	}
+
+	if _, err := tx.CopyFrom(
+		pgx.Identifier{"tx_data"},
+		[]string{"transaction_id", "vout", "hex", "text", "protocol"},
+		pgx.CopyFromRows(dataRows(trans)),
+	); err != nil {
+		return errors.Wrap(err, "transaction: data copy failed")
+	}
+	return nil
+}
+
+// spentRows return spent_output rows for every input which spends previous output
+func spentRows(trans []Transaction) [][]interface{} {
+	rows := make([][]interface{}, 0)
+	for _, t := range trans {
+		if t.IsCoinbase() {
+			continue
+		}
+
+		for i, in := range t.TxIns {
+			rows = append(rows, []interface{}{in.PrevOut, in.PrevIndex, t.Hash, uint32(i)})
+		}
+	}
+	return rows
+}
+
+// getSpentBy fill spent_by for outputs of all transactions with one query
+func (pg *PGStorage) getSpentBy(trans []Transaction) error {
+	if len(trans) == 0 {
+		return nil
+	}
+
+	// the same hash can be returned twice for duplicated coinbase transactions
+	byHash := make(map[string][]int, len(trans))
+	hashes := make([]string, 0, len(trans))
+	for i, t := range trans {
+		if _, ok := byHash[t.Hash]; !ok {
+			hashes = append(hashes, t.Hash)
+		}
+		byHash[t.Hash] = append(byHash[t.Hash], i)
+	}
+
+	rows, err := pg.con.Query(`SELECT hash, vout, spent_by, vin FROM spent_output WHERE hash = ANY($1::varchar[])`, hashes)
+	if err != nil {
+		return errors.Wrap(err, "transaction: Cannot select spent outputs")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hash string
+		var vout uint32
+		s := SpentBy{}
+		if err := rows.Scan(&hash, &vout, &s.Hash, &s.Input); err != nil {
+			return errors.Wrapf(err, "transaction: Cannot retrieve spent output for %s", hash)
+		}
+
+		for _, i := range byHash[hash] {
+			if int(vout) < len(trans[i].TxOuts) {
+				spent := s
+				trans[i].TxOuts[vout].SpentBy = &spent
+			}
+		}
+	}
+	return rows.Err()
+}
+
 // GetByWhere execute sql query for transaction and find txin/txout data
 func (pg *PGStorage) GetByWhere(sql string, val ...interface{}) ([]Transaction, error) {
 
@@ -75,13 +261,20 @@ func (pg *PGStorage) GetByWhere(sql string, val ...interface{}) ([]Transaction,
 
 	for rows.Next() {
 		t := Transaction{}
+		var txin, txout []byte
+		var version *int32
+		var lockTime *int64
 		if err := rows.Scan(
 			// Transaction
 			&t.ID, &t.BlockID, &t.Hash, &t.HasWitness,
 			// txin
-			&t.TxIns,
+			&txin,
 			// txout
-			&t.TxOuts,
+			&txout,
+			// fee
+			&t.Fee, &t.Size, &t.Weight,
+			// raw transaction fields
+			&version, &lockTime,
 		); err != nil {
 			if err == pgx.ErrNoRows {
 				return trans, err
@@ -89,7 +282,27 @@ func (pg *PGStorage) GetByWhere(sql string, val ...interface{}) ([]Transaction,
 			return trans, errors.Wrapf(err, "transaction: Cannot select transaction %s, %v", sql, val)
 		}
 
+		// rows imported before version and lock time were stored
+		if version == nil || lockTime == nil {
+			t.noHeader = true
+		} else {
+			t.Version, t.LockTime = *version, uint32(*lockTime)
+		}
+
+		if t.TxIns, err = DecodeTxIns(txin); err != nil {
+			return trans, errors.Wrapf(err, "transaction: %s", t.Hash)
+		}
+
+		if t.TxOuts, err = DecodeTxOuts(txout); err != nil {
+			return trans, errors.Wrapf(err, "transaction: %s", t.Hash)
+		}
+
 		for i, in := range t.TxIns {
+			// coinbase input do not spend any address
+			if in.AddressID == 0 {
+				continue
+			}
+
 			if err := pg.con.QueryRow("SELECT hash FROM address WHERE id=$1",
 				in.AddressID,
 			).Scan(&t.TxIns[i].Address); err != nil {
@@ -103,30 +316,182 @@ func (pg *PGStorage) GetByWhere(sql string, val ...interface{}) ([]Transaction,
 				return trans, errors.Wrapf(err, "transaction: Cannot decode pk_script, transaction hash index:script - %s, %d:%s", t.Hash, i, out.PkScript)
 			}
 		}
+
+		// transactions imported before fee and weight columns have zero size and weight
+		t.CalcFee()
+		t.DecodeScripts()
 		trans = append(trans, t)
 	}
+	if err := rows.Err(); err != nil {
+		return trans, errors.Wrapf(err, "transaction: Cannot select transaction %s, %v", sql, val)
+	}
 
+	if err := pg.getSpentBy(trans); err != nil {
+		return trans, err
+	}
 	return trans, nil
 }
 
 // GetPricePerTransaction loop over all transactions and gets price per each transaction
 func (pg *PGStorage) GetPricePerTransaction(trans []Transaction) error {
-	for _, t := range trans {
+	for i := range trans {
 
 		err := pg.con.QueryRow(`SELECT
 			price
 			FROM btc_price as bt JOIN block as bl on bt.created_at <= bl.created_at
 			WHERE bl.id = $1 
 			ORDER BY bt.created_at desc limit 1`,
-			t.BlockID,
-		).Scan(&t.Price)
+			trans[i].BlockID,
+		).Scan(&trans[i].Price)
 
 		if err == pgx.ErrNoRows {
-			log.Printf("block: Cannot get bitcoin price, trans: %s, err: %v", t.Hash, err)
-			t.Price = 0.00
-		} else {
+			log.Printf("block: Cannot get bitcoin price, trans: %s, err: %v", trans[i].Hash, err)
+			trans[i].Price = 0.00
+		} else if err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// GetLastPrice return the most recent bitcoin price, zero when prices are not imported
+func (pg *PGStorage) GetLastPrice() (float32, error) {
+	var price float32
+	err := pg.con.QueryRow(`SELECT price FROM btc_price ORDER BY created_at DESC LIMIT 1`).Scan(&price)
+
+	if err == pgx.ErrNoRows {
+		log.Printf("transaction: Cannot get last bitcoin price, err: %v", err)
+		return 0, nil
+	} else if err != nil {
+		return 0, errors.Wrap(err, "transaction: Cannot get last bitcoin price")
+	}
+	return price, nil
+}
+
+// GetBlockInfo fill height, hash and time of the block transaction was included in
+// and amount of confirmations
+func (pg *PGStorage) GetBlockInfo(t *Transaction) error {
+	err := pg.con.QueryRow(`SELECT
+		b.height, b.hash, b.created_at, (SELECT max(height) FROM block) - b.height + 1
+		FROM block as b
+		WHERE b.id = $1`,
+		t.BlockID,
+	).Scan(&t.BlockHeight, &t.BlockHash, &t.BlockTime, &t.Confirmations)
+
+	if err != nil {
+		return errors.Wrapf(err, "transaction: Cannot get block %d for transaction %s", t.BlockID, t.Hash)
+	}
+	return nil
+}
+
+// likeEscaper escape LIKE pattern characters in user query
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchData find embedded data by hex substring or case insensitive text
+func (pg *PGStorage) SearchData(hexQuery, text string, limit int) ([]DataMatch, error) {
+	matches := make([]DataMatch, 0)
+
+	rows, err := pg.con.Query(`
+		SELECT t.hash, d.vout, coalesce(b.height, 0), d.hex, d.text, d.protocol
+		FROM tx_data as d
+			JOIN transaction as t ON t.id = d.transaction_id
+			LEFT JOIN block as b ON b.id = t.block_id
+		WHERE ($1 != '' AND d.hex LIKE '%' || $1 || '%') OR lower(d.text) LIKE '%' || lower($2) || '%'
+		ORDER BY d.transaction_id DESC, d.vout
+		LIMIT $3`,
+		hexQuery,
+		likeEscaper.Replace(text),
+		limit,
+	)
+	if err != nil {
+		return matches, errors.Wrap(err, "transaction: cannot search data")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		m := DataMatch{}
+		if err := rows.Scan(&m.Hash, &m.Vout, &m.BlockHeight, &m.Hex, &m.Text, &m.Protocol); err != nil {
+			return matches, errors.Wrap(err, "transaction: cannot retrieve data")
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// Search select transactions according to filter, one extra transaction
+// is returned to show that next page exists
+func (pg *PGStorage) Search(f SearchFilter) ([]Transaction, error) {
+	where := "WHERE true"
+	args := make([]interface{}, 0)
+
+	cond := func(format string, v interface{}) {
+		args = append(args, v)
+		where += fmt.Sprintf(" AND "+format, len(args))
+	}
+
+	if f.FromHeight != nil {
+		cond("b.height >= $%d", *f.FromHeight)
+	}
+	if f.ToHeight != nil {
+		cond("b.height <= $%d", *f.ToHeight)
+	}
+	if !f.From.IsZero() {
+		cond("b.created_at >= $%d", f.From)
+	}
+	if !f.To.IsZero() {
+		cond("b.created_at < $%d", f.To)
+	}
+	if f.MinValue != nil {
+		cond("t.value >= $%d", *f.MinValue)
+	}
+	if f.MaxValue != nil {
+		cond("t.value <= $%d", *f.MaxValue)
+	}
+	if f.MinFee != nil {
+		cond("t.fee >= $%d", *f.MinFee)
+	}
+	if f.MaxFee != nil {
+		cond("t.fee <= $%d", *f.MaxFee)
+	}
+	if f.Address != "" {
+		cond("t.addresses @> (SELECT to_jsonb(id) FROM address WHERE hash = $%d)", f.Address)
+	}
+	if f.MinInputs != nil {
+		cond("jsonb_array_length(t.txin) >= $%d", *f.MinInputs)
+	}
+	if f.MaxInputs != nil {
+		cond("jsonb_array_length(t.txin) <= $%d", *f.MaxInputs)
+	}
+	if f.MinOutputs != nil {
+		cond("jsonb_array_length(t.txout) >= $%d", *f.MinOutputs)
+	}
+	if f.MaxOutputs != nil {
+		cond("jsonb_array_length(t.txout) <= $%d", *f.MaxOutputs)
+	}
+	if f.ScriptType != "" {
+		cond(`EXISTS (
+			SELECT 1 FROM jsonb_array_elements(t.txout) as o
+			WHERE o->>'script_type' = $%d`, f.ScriptType)
+		if pattern, ok := scriptPatterns[f.ScriptType]; ok {
+			args = append(args, pattern)
+			where += fmt.Sprintf(" OR (o->>'v' IS NULL AND o->>'pk_script' LIKE $%d)", len(args))
+		}
+		where += ")"
+	}
+
+	args = append(args, f.Limit+1, f.Offset)
+	sql := fmt.Sprintf(`SELECT
+			t.id, t.block_id, t.hash, t.has_witness, t.txin, t.txout, t.fee, t.size, t.weight, t.version, t.lock_time
+			FROM transaction as t JOIN block as b ON b.id = t.block_id
+			%s
+			ORDER BY %s %s, t.id %s
+			LIMIT $%d OFFSET $%d`,
+		where, sortColumns[f.Sort], f.Order, f.Order, len(args)-1, len(args),
+	)
+
+	trans, err := pg.GetByWhere(sql, args...)
+	if err != nil {
+		return trans, errors.Wrap(err, "transaction: cannot search transactions")
+	}
+	return trans, nil
+}
@@ -0,0 +1,332 @@
+package transaction
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// ErrBadDirection error for trace direction other than forward or backward
+var ErrBadDirection = fmt.Errorf("Wrong direction, use forward or backward")
+
+// ErrBadTaintMethod error for taint method other than haircut or fifo
+var ErrBadTaintMethod = fmt.Errorf("Wrong taint method, use haircut or fifo")
+
+// Trace directions and taint methods
+const (
+	TraceForward  = "forward"
+	TraceBackward = "backward"
+	TaintHaircut  = "haircut"
+	TaintFIFO     = "fifo"
+)
+
+// Trace limits to stop runaway queries
+const (
+	DefaultTraceDepth = 3
+	MaxTraceDepth     = 10
+	MaxTraceNodes     = 200
+)
+
+// TraceOptions parameters of fund flow tracing
+type TraceOptions struct {
+	Direction string
+	Method    string
+	Depth     int
+}
+
+// TraceNode is transaction reached by tracing, unresolved transaction
+// is not in database and is not expanded further
+type TraceNode struct {
+	Hash       string `json:"hash"`
+	Depth      int    `json:"depth"`
+	Value      int64  `json:"value"` // transaction output value
+	Taint      int64  `json:"taint"` // satoshi attributed to traced coins
+	Unresolved bool   `json:"unresolved,omitempty"`
+}
+
+// TraceEdge is output which moves traced coins between transactions,
+// To is empty for unspent output in forward direction
+type TraceEdge struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Vout    uint32 `json:"vout"`
+	Address string `json:"address"`
+	Value   int64  `json:"value"`
+	Taint   int64  `json:"taint"`
+}
+
+// TraceGraph is result of fund flow tracing
+type TraceGraph struct {
+	Root      string      `json:"root"`
+	Direction string      `json:"direction"`
+	Method    string      `json:"method"`
+	Depth     int         `json:"depth"`
+	Nodes     []TraceNode `json:"nodes"`
+	Edges     []TraceEdge `json:"edges"`
+	Truncated bool        `json:"truncated"` // node limit was reached
+}
+
+// normalize check options and apply defaults and limits
+func (o *TraceOptions) normalize() error {
+	if o.Direction == "" {
+		o.Direction = TraceForward
+	} else if o.Direction != TraceForward && o.Direction != TraceBackward {
+		return ErrBadDirection
+	}
+
+	if o.Method == "" {
+		o.Method = TaintHaircut
+	} else if o.Method != TaintHaircut && o.Method != TaintFIFO {
+		return ErrBadTaintMethod
+	}
+
+	if o.Depth <= 0 {
+		o.Depth = DefaultTraceDepth
+	} else if o.Depth > MaxTraceDepth {
+		o.Depth = MaxTraceDepth
+	}
+	return nil
+}
+
+// Trace follow coins of transaction forward to spending transactions or
+// backward to funding transactions. Every output of root transaction is
+// traced, amounts are attributed by haircut (proportional) or FIFO taint.
+// Transaction reached second time gets only an edge, it is not expanded again
+func Trace(reader Storage, hash string, opts TraceOptions) (*TraceGraph, error) {
+	if err := opts.normalize(); err != nil {
+		return nil, err
+	}
+
+	root, err := FindTransaction(reader, "hash", hash)
+	if err != nil {
+		return nil, err
+	}
+
+	// database may start after genesis, missing transactions are graph leaves
+	load := func(hash string) (*Transaction, error) {
+		t, err := FindTransaction(reader, "hash", hash)
+		if err == ErrNoTran {
+			return nil, nil
+		}
+		return &t, err
+	}
+	return trace(load, &root, opts)
+}
+
+// traceItem is transaction on trace level with taint per output (forward) or input (backward)
+type traceItem struct {
+	t     *Transaction
+	taint []int64
+	total int64 // taint of traced outputs
+}
+
+// trace walk transactions level by level with loader function,
+// loader return nil for transaction which is not in database
+func trace(load func(string) (*Transaction, error), root *Transaction, opts TraceOptions) (*TraceGraph, error) {
+	g := TraceGraph{
+		Root:      root.Hash,
+		Direction: opts.Direction,
+		Method:    opts.Method,
+		Depth:     opts.Depth,
+		Nodes:     make([]TraceNode, 0),
+		Edges:     make([]TraceEdge, 0),
+	}
+
+	// every output of root transaction is traced
+	rootItem := traceItem{t: root, taint: outputValues(root), total: root.OutputValue()}
+	if opts.Direction == TraceBackward {
+		rootItem.taint = spread(outputValues(root), rootItem.taint, inputValues(root), opts.Method)
+	}
+
+	visited := map[string]bool{root.Hash: true}
+	level := []traceItem{rootItem}
+
+	for depth := 0; len(level) > 0; depth++ {
+		// taint per input (forward) or output (backward) of next level transactions
+		nextTaint := make(map[string]map[uint32]int64)
+		nextHashes := make([]string, 0)
+		next := func(hash string, index uint32, taint int64) {
+			if depth >= opts.Depth || visited[hash] {
+				return
+			}
+			if _, ok := nextTaint[hash]; !ok {
+				nextTaint[hash] = make(map[uint32]int64)
+				nextHashes = append(nextHashes, hash)
+			}
+			nextTaint[hash][index] += taint
+		}
+
+		for _, item := range level {
+			t := item.t
+			g.Nodes = append(g.Nodes, TraceNode{Hash: t.Hash, Depth: depth, Value: t.OutputValue(), Taint: item.total})
+
+			if opts.Direction == TraceForward {
+				for vout, out := range t.TxOuts {
+					if item.taint[vout] == 0 {
+						continue
+					}
+
+					edge := TraceEdge{From: t.Hash, Vout: uint32(vout), Value: out.Value, Taint: item.taint[vout]}
+					if len(out.Addresses) > 0 {
+						edge.Address = out.Addresses[0]
+					}
+					if out.SpentBy != nil {
+						edge.To = out.SpentBy.Hash
+						next(edge.To, out.SpentBy.Input, edge.Taint)
+					}
+					g.Edges = append(g.Edges, edge)
+				}
+				continue
+			}
+
+			if t.IsCoinbase() {
+				continue
+			}
+
+			for vin, in := range t.TxIns {
+				if item.taint[vin] == 0 {
+					continue
+				}
+
+				g.Edges = append(g.Edges, TraceEdge{
+					From:    in.PrevOut,
+					To:      t.Hash,
+					Vout:    in.PrevIndex,
+					Address: in.Address,
+					Value:   in.Amount,
+					Taint:   item.taint[vin],
+				})
+				next(in.PrevOut, in.PrevIndex, item.taint[vin])
+			}
+		}
+
+		level = make([]traceItem, 0, len(nextHashes))
+		for _, hash := range nextHashes {
+			if len(g.Nodes)+len(level) >= MaxTraceNodes {
+				g.Truncated = true
+				break
+			}
+
+			t, err := load(hash)
+			if err != nil {
+				return &g, err
+			}
+			visited[hash] = true
+
+			if t == nil {
+				var taint int64
+				for _, v := range nextTaint[hash] {
+					taint += v
+				}
+				g.Nodes = append(g.Nodes, TraceNode{Hash: hash, Depth: depth + 1, Taint: taint, Unresolved: true})
+				continue
+			}
+
+			if opts.Direction == TraceForward {
+				in := indexed(nextTaint[hash], len(t.TxIns))
+				out := spread(inputValues(t), in, outputValues(t), opts.Method)
+				level = append(level, traceItem{t: t, taint: out, total: sum(out)})
+			} else {
+				out := indexed(nextTaint[hash], len(t.TxOuts))
+				in := spread(outputValues(t), out, inputValues(t), opts.Method)
+				level = append(level, traceItem{t: t, taint: in, total: sum(out)})
+			}
+		}
+	}
+
+	return &g, nil
+}
+
+// indexed convert taint map to slice, indexes out of range are ignored
+func indexed(taint map[uint32]int64, n int) []int64 {
+	res := make([]int64, n)
+	for index, v := range taint {
+		if int(index) < n {
+			res[index] = v
+		}
+	}
+	return res
+}
+
+// spread attribute taint of source values (inputs or outputs) to target values.
+// Haircut gives every target the same share, FIFO lays source and target
+// values in order and moves taint of overlapping parts
+func spread(from, taint, to []int64, method string) []int64 {
+	res := make([]int64, len(to))
+
+	if method == TaintHaircut {
+		total, toTotal := sum(from), sum(to)
+		// fee belongs to inputs, so denominator is the bigger side
+		if toTotal > total {
+			total = toTotal
+		}
+		if total == 0 {
+			return res
+		}
+
+		tainted := sum(taint)
+		for j := range to {
+			res[j] = mulDiv(to[j], tainted, total)
+		}
+		return res
+	}
+
+	var fromStart int64
+	for i := range from {
+		if taint[i] != 0 && from[i] != 0 {
+			var toStart int64
+			for j := range to {
+				overlap := min64(fromStart+from[i], toStart+to[j]) - max64(fromStart, toStart)
+				if overlap > 0 {
+					res[j] += mulDiv(overlap, taint[i], from[i])
+				}
+				toStart += to[j]
+			}
+		}
+		fromStart += from[i]
+	}
+	return res
+}
+
+// mulDiv return a*b/c without int64 overflow
+func mulDiv(a, b, c int64) int64 {
+	r := new(big.Int).Mul(big.NewInt(a), big.NewInt(b))
+	return r.Quo(r, big.NewInt(c)).Int64()
+}
+
+func inputValues(t *Transaction) []int64 {
+	values := make([]int64, len(t.TxIns))
+	for i, in := range t.TxIns {
+		values[i] = in.Amount
+	}
+	return values
+}
+
+func outputValues(t *Transaction) []int64 {
+	values := make([]int64, len(t.TxOuts))
+	for i, out := range t.TxOuts {
+		values[i] = out.Value
+	}
+	return values
+}
+
+func sum(values []int64) int64 {
+	var total int64
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
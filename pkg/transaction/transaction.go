@@ -2,7 +2,7 @@ package transaction
 
 import (
 	"fmt"
-	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -10,17 +10,35 @@ import (
 // ErrNoTran Error message
 var ErrNoTran = fmt.Errorf("No transaction found")
 
+// zeroHash is previous output hash of coinbase input
+const zeroHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
 // Transaction holds transaction data and in/out array
 type Transaction struct {
-	ID         uint    `json:"id"`
-	BlockID    uint    `json:"block_id"`
-	Hash       string  `json:"hash"`
-	HasWitness bool    `json:"has_witness"`
-	Price      float32 `json:"price"`
-	TxIns      []TxIn  `json:"txins"`
-	TxOuts     []TxOut `json:"txouts"`
-	Addresses  []uint
-	storage    Storage
+	ID              uint    `json:"id"`
+	BlockID         uint    `json:"block_id"`
+	Hash            string  `json:"hash"`
+	HasWitness      bool    `json:"has_witness"`
+	Version         int32   `json:"version"`
+	LockTime        uint32  `json:"lock_time"`
+	Price           float32 `json:"price"`
+	Fee             int64   `json:"fee"`
+	Size            int     `json:"size"`
+	VSize           int     `json:"vsize"`
+	Weight          int     `json:"weight"`
+	WitnessDiscount int     `json:"witness_discount"` // bytes saved by witness discount, size minus vsize
+	FeeRate         float64 `json:"fee_rate"`         // satoshi per virtual byte
+	TxIns           []TxIn  `json:"txins"`
+	TxOuts          []TxOut `json:"txouts"`
+	Addresses       []uint
+	storage         Storage
+	noHeader        bool // version and lock time were not stored, transaction cannot be rebuilt
+
+	// block data, filled only for transaction details
+	BlockHeight   int32      `json:"block_height,omitempty"`
+	BlockHash     string     `json:"block_hash,omitempty"`
+	BlockTime     *time.Time `json:"block_time,omitempty"`
+	Confirmations int32      `json:"confirmations,omitempty"`
 }
 
 // New constructor
@@ -41,14 +59,14 @@ func FindTransactions(reader Storage, key string, val interface{}) ([]Transactio
 
 	if key == "address_hash" {
 		sql = fmt.Sprintf(`SELECT
-				id, block_id, hash, has_witness, txin, txout 
+				id, block_id, hash, has_witness, txin, txout, fee, size, weight, version, lock_time
 				FROM transaction where addresses@>'%d'
 				ORDER BY id desc`, val)
 		return reader.GetByWhere(sql)
 	}
 
 	sql = fmt.Sprintf(`SELECT
-			id, block_id, hash, has_witness, txin, txout 
+			id, block_id, hash, has_witness, txin, txout, fee, size, weight, version, lock_time
 			FROM transaction as t
 			WHERE %s = $1
 			ORDER BY t.id desc`, key)
@@ -60,7 +78,7 @@ func FindTransactions(reader Storage, key string, val interface{}) ([]Transactio
 func FindTransaction(reader Storage, key string, val interface{}) (Transaction, error) {
 
 	sql := fmt.Sprintf(`SELECT
-			id, block_id, hash, has_witness, txin, txout 
+			id, block_id, hash, has_witness, txin, txout, fee, size, weight, version, lock_time
 			FROM transaction as t
 			WHERE %s = $1`, key)
 
@@ -74,86 +92,53 @@ func FindTransaction(reader Storage, key string, val interface{}) (Transaction,
 	return trans[0], nil
 }
 
-// GetPricePerTransaction loop over all transactions and gets price per each transaction
-func GetPricePerTransaction(reader Storage, trans []Transaction) error {
-	return reader.GetPricePerTransaction(trans)
-}
+// GetTransactionDetail find transaction by hash with block data,
+// confirmations and bitcoin price on the moment of inclusion
+func GetTransactionDetail(reader Storage, hash string) (Transaction, error) {
+	t, err := FindTransaction(reader, "hash", hash)
+	if err != nil {
+		return t, err
+	}
 
-// TxOutJSONB transform TxOut array for pg jsonb insert
-func (t *Transaction) TxOutJSONB() string {
-	if len(t.TxOuts) == 0 {
-		return "[]"
+	if err := reader.GetBlockInfo(&t); err != nil {
+		return t, err
 	}
 
-	pgQuery := ""
-	for _, out := range t.TxOuts {
-		pgQuery = fmt.Sprintf(`%s,{
-  "val": %d,
-  "pk_script": "%s"}`,
-			pgQuery,
-			out.Value,
-			out.PkScript,
-		)
+	trans := []Transaction{t}
+	if err := reader.GetPricePerTransaction(trans); err != nil {
+		return t, errors.Wrapf(err, "transaction: Cannot get price for %s", hash)
 	}
-	pgQuery = strings.Replace(
-		fmt.Sprintf("[%s]", pgQuery[1:len(pgQuery)]),
-		"\n",
-		"",
-		-1,
-	)
-	return pgQuery
+	return trans[0], nil
 }
 
-// TxInJSONB transform TxIn array for pg jsonb insert
-func (t *Transaction) TxInJSONB() string {
+// GetPricePerTransaction loop over all transactions and gets price per each transaction
+func GetPricePerTransaction(reader Storage, trans []Transaction) error {
+	return reader.GetPricePerTransaction(trans)
+}
+
+// IsCoinbase return true for block reward transaction,
+// coinbase has only one input without previous output
+func (t *Transaction) IsCoinbase() bool {
 	if len(t.TxIns) == 0 {
-		return "[]"
+		return true
 	}
+	return len(t.TxIns) == 1 && (t.TxIns[0].PrevOut == "" || t.TxIns[0].PrevOut == zeroHash)
+}
 
-	pgQuery := ""
+// InputValue return sum of all incoming amounts
+func (t *Transaction) InputValue() int64 {
+	var total int64
 	for _, in := range t.TxIns {
-		pgQuery = fmt.Sprintf(`%s,{
-  "amount": %d,
-  "address_id": %d,
-  "address": "%s",
-  "prev_out": "%s",
-  "size": %d,
-  "signature_script": "%s",
-  "sequence": %d,
-  "witness": "%s"}`,
-			pgQuery,
-			in.Amount,
-			in.AddressID,
-			in.Address,
-			in.PrevOut,
-			in.Size,
-			in.SignatureScript,
-			in.Sequence,
-			in.Witness,
-		)
+		total += in.Amount
 	}
-	pgQuery = strings.Replace(
-		fmt.Sprintf("[%s]", pgQuery[1:len(pgQuery)]),
-		"\n",
-		"",
-		-1,
-	)
-	return pgQuery
+	return total
 }
 
-// AddressesJSONB transform Addresses array for pg jsonb insert
-func (t *Transaction) AddressesJSONB() string {
-	if len(t.Addresses) == 0 {
-		return "[]"
-	}
-
-	pgQuery := ""
-	for _, addr := range t.Addresses {
-		pgQuery = fmt.Sprintf("%s,%d",
-			pgQuery,
-			addr,
-		)
+// OutputValue return sum of all outcoming values
+func (t *Transaction) OutputValue() int64 {
+	var total int64
+	for _, out := range t.TxOuts {
+		total += out.Value
 	}
-	pgQuery = fmt.Sprintf("[%s]", pgQuery[1:len(pgQuery)])
-	return pgQuery
+	return total
 }
@@ -1,10 +1,20 @@
 package transaction
 
 import (
+	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"io/ioutil"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
 	"github.com/pkg/errors"
 )
 
@@ -26,10 +36,21 @@ func readJSONFile(path string, out interface{}) error {
 	return nil
 }
 
+func (s FakeStorage) Insert(t *Transaction) error {
+	t.ID = 1
+	return nil
+}
+
 func (s FakeStorage) GetByWhere(sql string, val ...interface{}) ([]Transaction, error) {
 	trans := make([]Transaction, 0)
 
-	if val[0].(string) == "good_wallet" {
+	// address_hash search put value directly to the sql
+	key := sql
+	if len(val) > 0 {
+		key, _ = val[0].(string)
+	}
+
+	if strings.Contains(key, "good_wallet") {
 		// Txin + Txouts
 		txin1 := make([]TxIn, 0)
 		err := readJSONFile("fixtures/txin_1.json", &txin1)
@@ -75,7 +96,7 @@ func (s FakeStorage) GetByWhere(sql string, val ...interface{}) ([]Transaction,
 		}, nil
 	}
 
-	if val[0].(string) == "bad_wallet" {
+	if strings.Contains(key, "bad_wallet") {
 		return trans, nil
 	}
 
@@ -89,6 +110,31 @@ func (s FakeStorage) GetPricePerTransaction(trans []Transaction) error {
 	return nil
 }
 
+func (s FakeStorage) GetLastPrice() (float32, error) {
+	return 40000, nil
+}
+
+func (s FakeStorage) GetBlockInfo(t *Transaction) error {
+	t.BlockHeight = 154734
+	t.Confirmations = 10
+	return nil
+}
+
+func (s FakeStorage) SearchData(hexQuery, text string, limit int) ([]DataMatch, error) {
+	m := DataMatch{Hash: hexQuery, BlockHeight: int32(limit)}
+	m.Text = text
+	return []DataMatch{m}, nil
+}
+
+func (s FakeStorage) Search(f SearchFilter) ([]Transaction, error) {
+	// first page has next page, any other is the last
+	n := f.Limit
+	if f.Offset == 0 {
+		n++
+	}
+	return make([]Transaction, n), nil
+}
+
 func TestFindTransactions(t *testing.T) {
 	t.Parallel()
 	f := FakeStorage{}
@@ -127,7 +173,7 @@ func TestGetAddresses(t *testing.T) {
 		t.Errorf("Cannot read json txout_1.json, %v", err)
 	}
 
-	addrs, err := txout1[0].getAddresses()
+	addrs, err := txout1[0].GetAddresses()
 
 	if err != nil {
 		t.Error(err)
@@ -145,7 +191,7 @@ func TestGetAddresses(t *testing.T) {
 		t.Errorf("Cannot read json txout_2.json, %v", err)
 	}
 
-	addrs, err = txout1[1].getAddresses()
+	addrs, err = txout1[1].GetAddresses()
 
 	if err != nil {
 		t.Error(err)
@@ -169,3 +215,789 @@ func TestGetPricePerTransaction(t *testing.T) {
 		}
 	}
 }
+
+func TestIsCoinbase(t *testing.T) {
+	t.Parallel()
+
+	txin1 := make([]TxIn, 0)
+	if err := readJSONFile("fixtures/txin_1.json", &txin1); err != nil {
+		t.Errorf("Cannot read json txin_1.json, %v", err)
+	}
+
+	tr := Transaction{TxIns: txin1}
+	if tr.IsCoinbase() {
+		t.Errorf("Transaction with %d inputs should not be coinbase", len(txin1))
+	}
+
+	tr.TxIns = []TxIn{{PrevOut: zeroHash}}
+	if !tr.IsCoinbase() {
+		t.Errorf("Transaction with zero prev_out should be coinbase")
+	}
+}
+
+func TestInputOutputValue(t *testing.T) {
+	t.Parallel()
+
+	tr := Transaction{
+		TxIns:  []TxIn{{Amount: 100}, {Amount: 50}},
+		TxOuts: []TxOut{{Value: 120}, {Value: 20}},
+	}
+
+	if tr.InputValue() != 150 {
+		t.Errorf("InputValue return wrong amount should 150, got: %d", tr.InputValue())
+	}
+
+	if tr.OutputValue() != 140 {
+		t.Errorf("OutputValue return wrong amount should 140, got: %d", tr.OutputValue())
+	}
+}
+
+func TestFromWire(t *testing.T) {
+	t.Parallel()
+
+	msg := wire.NewMsgTx(2)
+	msg.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, []byte{0x02, 0xab, 0xcd}, [][]byte{{0x01}, {0x02, 0x03}}))
+	msg.TxIn[0].PreviousOutPoint.Hash[0] = 0x01
+	msg.AddTxOut(wire.NewTxOut(1000, []byte{0x6a}))
+
+	tr := FromWire(msg)
+	if tr.Hash != msg.TxHash().String() || !tr.HasWitness {
+		t.Errorf("FromWire return wrong transaction, %+v", tr)
+	}
+
+	if tr.TxIns[0].SignatureScript != "abcd" || tr.TxIns[0].Witness != "01 0203" {
+		t.Errorf("FromWire return wrong input scripts, %+v", tr.TxIns[0])
+	}
+
+	if tr.TxOuts[0].PkScript != "6a" || tr.TxOuts[0].Value != 1000 {
+		t.Errorf("FromWire return wrong output, %+v", tr.TxOuts[0])
+	}
+
+	outpoint := msg.TxIn[0].PreviousOutPoint.String()
+	if tr.TxIns[0].PrevIndex != 1 || tr.TxIns[0].Outpoint() != outpoint {
+		t.Errorf("FromWire return wrong outpoint should %s, got: %s", outpoint, tr.TxIns[0].Outpoint())
+	}
+}
+
+func TestSpentRows(t *testing.T) {
+	t.Parallel()
+
+	coinbase := Transaction{Hash: "cb", TxIns: []TxIn{{PrevOut: zeroHash}}}
+	spend := Transaction{Hash: "tx", TxIns: []TxIn{{PrevOut: "a", PrevIndex: 3}, {PrevOut: "b"}}}
+
+	rows := spentRows([]Transaction{coinbase, spend})
+	if len(rows) != 2 {
+		t.Fatalf("spentRows should skip coinbase and return 2 rows, got: %v", rows)
+	}
+
+	if rows[0][0] != "a" || rows[0][1] != uint32(3) || rows[0][2] != "tx" || rows[0][3] != uint32(0) {
+		t.Errorf("spentRows return wrong row, %v", rows[0])
+	}
+
+	if rows[1][0] != "b" || rows[1][3] != uint32(1) {
+		t.Errorf("spentRows return wrong input index, %v", rows[1])
+	}
+}
+
+func TestParseCoinbase(t *testing.T) {
+	t.Parallel()
+
+	tr := Transaction{TxIns: []TxIn{{SignatureScript: "035b7a0300ff2f736c7573682f004d696e6564206279206d65"}}}
+	c, err := tr.ParseCoinbase()
+	if err != nil {
+		t.Fatalf("ParseCoinbase return error: %v", err)
+	}
+
+	if c.Height == nil || *c.Height != 227931 {
+		t.Errorf("ParseCoinbase return wrong height should 227931, got: %v", c.Height)
+	}
+
+	if len(c.Tags) != 2 || c.Tags[0] != "/slush/" || c.Tags[1] != "Mined by me" {
+		t.Errorf("ParseCoinbase return wrong tags, %q", c.Tags)
+	}
+
+	// disassembled script from btcd2sql
+	tr.TxIns[0].SignatureScript = "04ffff001d 0104 5468652054696d6573"
+	if c, err = tr.ParseCoinbase(); err != nil || len(c.Tags) != 1 {
+		t.Errorf("ParseCoinbase return wrong tags for disassembled script, %+v, %v", c, err)
+	}
+
+	tr.TxIns = append(tr.TxIns, TxIn{PrevOut: "a"})
+	if _, err := tr.ParseCoinbase(); err != ErrNotCoinbase {
+		t.Errorf("ParseCoinbase return wrong error, should: %v, got: %v", ErrNotCoinbase, err)
+	}
+}
+
+func TestCalcFee(t *testing.T) {
+	t.Parallel()
+
+	msg := wire.NewMsgTx(1)
+	msg.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 0}, make([]byte, 107), nil))
+	msg.TxIn[0].PreviousOutPoint.Hash[0] = 0x01
+	msg.AddTxOut(wire.NewTxOut(9000, make([]byte, 25)))
+
+	tr := FromWire(msg)
+	tr.TxIns[0].Amount = 10000
+	tr.Size, tr.VSize = 0, 0
+	tr.CalcFee()
+
+	if tr.Size != msg.SerializeSize() || tr.VSize != tr.Size {
+		t.Errorf("CalcFee return wrong size should %d, got: %d, vsize: %d", msg.SerializeSize(), tr.Size, tr.VSize)
+	}
+
+	if tr.Fee != 1000 || tr.FeeRate != float64(1000)/float64(tr.VSize) {
+		t.Errorf("CalcFee return wrong fee, %d, rate: %f", tr.Fee, tr.FeeRate)
+	}
+
+	msg.TxIn[0].Witness = [][]byte{make([]byte, 72), make([]byte, 33)}
+	segwit := FromWire(msg)
+	vsize, weight := segwit.VSize, segwit.Weight
+	segwit.Size, segwit.VSize, segwit.Weight = 0, 0, 0
+	segwit.CalcFee()
+
+	if segwit.Size != msg.SerializeSize() || segwit.VSize != vsize {
+		t.Errorf("CalcFee return wrong witness size should %d/%d, got: %d/%d", msg.SerializeSize(), vsize, segwit.Size, segwit.VSize)
+	}
+
+	if segwit.Weight != weight || weight != msg.SerializeSizeStripped()*3+msg.SerializeSize() {
+		t.Errorf("CalcFee return wrong weight should %d, got: %d", weight, segwit.Weight)
+	}
+
+	if segwit.WitnessDiscount != segwit.Size-segwit.VSize || segwit.WitnessDiscount <= 0 {
+		t.Errorf("CalcFee return wrong witness discount, %d", segwit.WitnessDiscount)
+	}
+
+	coinbase := Transaction{TxIns: []TxIn{{}}, TxOuts: []TxOut{{Value: 5000000000}}}
+	coinbase.CalcFee()
+	if coinbase.Fee != 0 {
+		t.Errorf("CalcFee should return zero fee for coinbase, got: %d", coinbase.Fee)
+	}
+}
+
+func TestScriptType(t *testing.T) {
+	t.Parallel()
+
+	scripts := map[string]string{
+		"76a914d4acec9b747f438152505781406b958548d1b62a88ac":                   ScriptP2PKH,
+		"a914748284390f9e263a4b766a75d0633c50426eb87587":                       ScriptP2SH,
+		"0014751e76e8199196d454941c45d1b3a323f1433bd6":                         ScriptP2WPKH,
+		"00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262": ScriptP2WSH,
+		"5120a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c": ScriptP2TR,
+		"6a24aa21a9ed": ScriptOpReturn,
+		"21034f355bdcb7cc0af728ef3cceb9615d90684bb5b2ca5f859ab0f0b704075871aaac": ScriptP2PK,
+		"ac": ScriptNonStandard,
+	}
+
+	for script, typ := range scripts {
+		out := TxOut{PkScript: script}
+		out.DecodeScript()
+		if out.ScriptType != typ {
+			t.Errorf("DecodeScript return wrong type for %s should %s, got: %s", script, typ, out.ScriptType)
+		}
+	}
+
+	out := TxOut{PkScript: "76a914d4acec9b747f438152505781406b958548d1b62a88ac"}
+	out.DecodeScript()
+	if out.Asm != "OP_DUP OP_HASH160 d4acec9b747f438152505781406b958548d1b62a OP_EQUALVERIFY OP_CHECKSIG" {
+		t.Errorf("DecodeScript return wrong asm, %s", out.Asm)
+	}
+}
+
+func TestDecodeInputScript(t *testing.T) {
+	t.Parallel()
+
+	sig := "3006020101020101" + "01"
+	pubkey := "034f355bdcb7cc0af728ef3cceb9615d90684bb5b2ca5f859ab0f0b704075871aa"
+
+	p2pkh := TxIn{SignatureScript: sig + " " + pubkey}
+	p2pkh.DecodeScript(false)
+	if p2pkh.Signature != sig || p2pkh.PubKey != pubkey || p2pkh.Asm != p2pkh.SignatureScript {
+		t.Errorf("DecodeScript return wrong p2pkh input data, %+v", p2pkh)
+	}
+
+	p2wpkh := TxIn{Witness: sig + " " + pubkey}
+	p2wpkh.DecodeScript(false)
+	if p2wpkh.Signature != sig || p2wpkh.PubKey != pubkey {
+		t.Errorf("DecodeScript return wrong witness input data, %+v", p2wpkh)
+	}
+
+	schnorr := strings.Repeat("ab", 64)
+	p2tr := TxIn{Witness: schnorr}
+	p2tr.DecodeScript(false)
+	if p2tr.Signature != schnorr || p2tr.PubKey != "" {
+		t.Errorf("DecodeScript return wrong taproot input data, %+v", p2tr)
+	}
+
+	coinbase := TxIn{SignatureScript: "04ffff001d0104"}
+	coinbase.DecodeScript(true)
+	if coinbase.Asm != "ffff001d 04" {
+		t.Errorf("DecodeScript return wrong coinbase asm, %s", coinbase.Asm)
+	}
+}
+
+func TestParseData(t *testing.T) {
+	t.Parallel()
+
+	if d := ParseData([]byte{0x76, 0xa9}); d != nil {
+		t.Errorf("ParseData should return nil for non OP_RETURN script, got: %+v", d)
+	}
+
+	script, _ := hex.DecodeString("6a146f6d6e69000000000000001f000000002faf0800")
+	d := ParseData(script)
+	if d == nil || d.Protocol != "omni" || d.Hex != "6f6d6e69000000000000001f000000002faf0800" {
+		t.Errorf("ParseData return wrong omni payload, %+v", d)
+	}
+
+	d = ParseData(append([]byte{0x6a, 0x0b}, []byte("hello world")...))
+	if d == nil || d.Text != "hello world" || d.Protocol != "" {
+		t.Errorf("ParseData return wrong text payload, %+v", d)
+	}
+
+	out := TxOut{PkScript: "6a0b68656c6c6f20776f726c64"}
+	if addrs, err := out.GetAddresses(); err != nil || len(addrs) != 0 {
+		t.Errorf("GetAddresses should return no addresses for OP_RETURN, got: %v, %v", addrs, err)
+	}
+}
+
+func TestSearchData(t *testing.T) {
+	t.Parallel()
+
+	f := FakeStorage{}
+	if _, err := SearchData(f, " ", 0); err != ErrEmptyQuery {
+		t.Errorf("SearchData return wrong error, should: %v, got: %v", ErrEmptyQuery, err)
+	}
+
+	m, err := SearchData(f, "CAFE", 1000)
+	if err != nil || m[0].Hash != "cafe" || m[0].BlockHeight != MaxDataLimit {
+		t.Errorf("SearchData should search hex in lower case with max limit, got: %+v, %v", m, err)
+	}
+
+	m, _ = SearchData(f, "hello", 0)
+	if m[0].Hash != "" || m[0].Text != "hello" || m[0].BlockHeight != DefaultDataLimit {
+		t.Errorf("SearchData should search only text for non hex query, got: %+v", m)
+	}
+}
+
+func TestNonstandardAddress(t *testing.T) {
+	t.Parallel()
+
+	// P2PK with public key which is not on the curve
+	pubKey := "02" + strings.Repeat("00", 32)
+	out := TxOut{PkScript: "21" + pubKey + "ac"}
+	addrs, err := out.GetAddresses()
+	if err != nil || len(addrs) != 1 || !strings.HasPrefix(addrs[0], "1") {
+		t.Errorf("GetAddresses should return P2PKH address for malformed P2PK, got: %v, %v", addrs, err)
+	}
+
+	a := TxOut{PkScript: "0101ac"}
+	b := TxOut{PkScript: "0102ac"}
+	addrA, _ := a.GetAddresses()
+	addrB, _ := b.GetAddresses()
+
+	if len(addrA[0]) != len(NonstandardPrefix)+64 || addrA[0] == addrB[0] {
+		t.Errorf("GetAddresses should key nonstandard scripts by full script hash, got: %v, %v", addrA, addrB)
+	}
+}
+
+func TestJSONBRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, n := range []string{"1", "2"} {
+		txin, err := ioutil.ReadFile("fixtures/txin_" + n + ".json")
+		if err != nil {
+			t.Fatalf("Cannot read txin_%s.json, %v", n, err)
+		}
+		txout, err := ioutil.ReadFile("fixtures/txout_" + n + ".json")
+		if err != nil {
+			t.Fatalf("Cannot read txout_%s.json, %v", n, err)
+		}
+
+		tr := Transaction{Addresses: []uint{1, 2}}
+		if tr.TxIns, err = DecodeTxIns(txin); err != nil {
+			t.Fatalf("DecodeTxIns cannot decode fixture %s, %v", n, err)
+		}
+		if tr.TxOuts, err = DecodeTxOuts(txout); err != nil {
+			t.Fatalf("DecodeTxOuts cannot decode fixture %s, %v", n, err)
+		}
+
+		if tr.TxOuts[0].ScriptType != ScriptP2PKH || tr.TxOuts[0].AddressID == 0 {
+			t.Errorf("DecodeTxOuts should upgrade version 1 output, %+v", tr.TxOuts[0])
+		}
+
+		inJSONB, err := tr.TxInJSONB()
+		if err != nil {
+			t.Fatalf("TxInJSONB return error for fixture %s, %v", n, err)
+		}
+		ins, err := DecodeTxIns([]byte(inJSONB))
+		if err != nil || !reflect.DeepEqual(ins, tr.TxIns) {
+			t.Errorf("TxInJSONB round trip mismatch for fixture %s, %v", n, err)
+		}
+
+		outJSONB, err := tr.TxOutJSONB()
+		if err != nil {
+			t.Fatalf("TxOutJSONB return error for fixture %s, %v", n, err)
+		}
+		outs, err := DecodeTxOuts([]byte(outJSONB))
+		if err != nil || len(outs) != len(tr.TxOuts) || len(outs[0].Addresses) != 1 {
+			t.Fatalf("TxOutJSONB should store output addresses for fixture %s, %+v, %v", n, outs, err)
+		}
+
+		tr.TxOuts[0].Addresses = nil
+		addrs, _ := tr.TxOuts[0].GetAddresses()
+		if outs[0].Addresses[0] != addrs[0] || outs[0].ScriptType != ScriptP2PKH || outs[0].AddressID != tr.TxOuts[0].AddressID {
+			t.Errorf("TxOutJSONB round trip mismatch for fixture %s, %+v", n, outs[0])
+		}
+
+		if addresses, err := tr.AddressesJSONB(); err != nil || addresses != "[1,2]" {
+			t.Errorf("AddressesJSONB return wrong json, %s, %v", addresses, err)
+		}
+	}
+
+	// quotes and backslashes were breaking json built with fmt.Sprintf
+	tr := Transaction{TxIns: []TxIn{{SignatureScript: `"\`, Witness: `a"b`}}}
+	txin, _ := tr.TxInJSONB()
+	ins, err := DecodeTxIns([]byte(txin))
+	if err != nil || ins[0].SignatureScript != `"\` || ins[0].Witness != `a"b` {
+		t.Errorf("TxInJSONB should escape strings, %+v, %v", ins, err)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	t.Parallel()
+
+	graph := map[string]*Transaction{
+		"R": {
+			Hash:   "R",
+			TxIns:  []TxIn{{PrevOut: "P", Amount: 100}},
+			TxOuts: []TxOut{{Value: 60, SpentBy: &SpentBy{Hash: "A"}}, {Value: 40}},
+		},
+		"X": {
+			Hash:   "X",
+			TxIns:  []TxIn{{PrevOut: zeroHash}},
+			TxOuts: []TxOut{{Value: 40, SpentBy: &SpentBy{Hash: "A", Input: 1}}},
+		},
+		"A": {
+			Hash:   "A",
+			TxIns:  []TxIn{{PrevOut: "R", Amount: 60}, {PrevOut: "X", Amount: 40}},
+			TxOuts: []TxOut{{Value: 50, SpentBy: &SpentBy{Hash: "B"}}, {Value: 45}},
+		},
+		"B": {
+			Hash:   "B",
+			TxIns:  []TxIn{{PrevOut: "A", Amount: 50}},
+			TxOuts: []TxOut{{Value: 50}},
+		},
+	}
+	load := func(hash string) (*Transaction, error) {
+		return graph[hash], nil
+	}
+
+	taint := func(g *TraceGraph) map[string]int64 {
+		res := make(map[string]int64)
+		for _, n := range g.Nodes {
+			res[n.Hash] = n.Taint
+		}
+		return res
+	}
+
+	opts := TraceOptions{}
+	if err := opts.normalize(); err != nil || opts.Direction != TraceForward || opts.Method != TaintHaircut || opts.Depth != DefaultTraceDepth {
+		t.Errorf("normalize return wrong defaults, %+v, %v", opts, err)
+	}
+
+	g, _ := trace(load, graph["R"], opts)
+	if n := taint(g); len(n) != 3 || n["R"] != 100 || n["A"] != 57 || n["B"] != 30 {
+		t.Errorf("trace return wrong forward haircut taint, %+v", g.Nodes)
+	}
+
+	opts.Method = TaintFIFO
+	g, _ = trace(load, graph["R"], opts)
+	if n := taint(g); n["A"] != 60 || n["B"] != 50 {
+		t.Errorf("trace return wrong forward fifo taint, %+v", g.Nodes)
+	}
+
+	opts.Depth = 1
+	g, _ = trace(load, graph["R"], opts)
+	if len(g.Nodes) != 2 || len(g.Edges) != 4 {
+		t.Errorf("trace should stop at depth 1, %+v, %+v", g.Nodes, g.Edges)
+	}
+
+	opts = TraceOptions{Direction: TraceBackward, Method: TaintHaircut, Depth: 5}
+	g, _ = trace(func(hash string) (*Transaction, error) {
+		if hash == "P" {
+			return &Transaction{Hash: "P", TxIns: []TxIn{{}}, TxOuts: []TxOut{{Value: 100}}}, nil
+		}
+		return load(hash)
+	}, graph["B"], opts)
+	if n := taint(g); len(n) != 5 || n["B"] != 50 || n["A"] != 50 || n["R"] != 30 || n["X"] != 20 || n["P"] != 30 {
+		t.Errorf("trace return wrong backward haircut taint, %+v", g.Nodes)
+	}
+
+	// P is before the first imported block
+	g, err := trace(load, graph["B"], opts)
+	if err != nil || len(g.Nodes) != 5 || g.Nodes[4].Hash != "P" || !g.Nodes[4].Unresolved || g.Nodes[4].Taint != 30 || g.Nodes[4].Depth != 3 {
+		t.Errorf("trace should keep missing transaction as unresolved leaf, %+v, %v", g.Nodes, err)
+	}
+
+	opts.Method = TaintFIFO
+	opts.Depth = 2
+	g, _ = trace(load, graph["B"], opts)
+	if n := taint(g); len(n) != 3 || n["R"] != 50 || n["X"] != 0 {
+		t.Errorf("trace return wrong backward fifo taint, %+v", g.Nodes)
+	}
+
+	if err := (&TraceOptions{Direction: "up"}).normalize(); err != ErrBadDirection {
+		t.Errorf("normalize return wrong error, should: %v, got: %v", ErrBadDirection, err)
+	}
+	if err := (&TraceOptions{Method: "lifo"}).normalize(); err != ErrBadTaintMethod {
+		t.Errorf("normalize return wrong error, should: %v, got: %v", ErrBadTaintMethod, err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	f := FakeStorage{}
+	page, err := Search(f, SearchFilter{})
+	if err != nil || len(page.Transactions) != DefaultSearchLimit || page.NextOffset == nil || *page.NextOffset != DefaultSearchLimit {
+		t.Errorf("Search return wrong first page, %d, %v, %v", len(page.Transactions), page.NextOffset, err)
+	}
+
+	page, err = Search(f, SearchFilter{Limit: 1000, Offset: 100, Sort: "fee", Order: "asc", ScriptType: ScriptP2WPKH})
+	if err != nil || len(page.Transactions) != MaxSearchLimit || page.NextOffset != nil {
+		t.Errorf("Search return wrong last page, %d, %v, %v", len(page.Transactions), page.NextOffset, err)
+	}
+
+	if _, err := Search(f, SearchFilter{Sort: "hash"}); err != ErrBadSort {
+		t.Errorf("Search return wrong error, should: %v, got: %v", ErrBadSort, err)
+	}
+
+	if _, err := Search(f, SearchFilter{ScriptType: "p2xx"}); err != ErrBadScriptType {
+		t.Errorf("Search return wrong error, should: %v, got: %v", ErrBadScriptType, err)
+	}
+
+	min, max := int64(10), int64(5)
+	if _, err := Search(f, SearchFilter{MinFee: &min, MaxFee: &max}); err != ErrBadRange {
+		t.Errorf("Search return wrong error, should: %v, got: %v", ErrBadRange, err)
+	}
+
+	// version 1 outputs are matched by pk_script pattern
+	scripts := map[string]string{
+		ScriptP2PKH:  "76a914d4acec9b747f438152505781406b958548d1b62a88ac",
+		ScriptP2SH:   "a914748284390f9e263a4b766a75d0633c50426eb87587",
+		ScriptP2WPKH: "0014751e76e8199196d454941c45d1b3a323f1433bd6",
+		ScriptP2WSH:  "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262",
+		ScriptP2TR:   "5120a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c",
+	}
+	for typ, script := range scripts {
+		pattern := scriptPatterns[typ]
+		if len(pattern) != len(script) || script[:4] != pattern[:4] {
+			t.Errorf("scriptPatterns has wrong pattern for %s, %s", typ, pattern)
+		}
+	}
+}
+
+func TestInputScriptType(t *testing.T) {
+	t.Parallel()
+
+	sig := "300602010102010101"
+	pub := "02" + strings.Repeat("11", 32)
+	redeem := "5121" + pub + "51ae"
+
+	for typ, in := range map[string]TxIn{
+		ScriptP2PK:        {SignatureScript: sig},
+		ScriptP2PKH:       {SignatureScript: sig + " " + pub},
+		ScriptMultisig:    {SignatureScript: "0 " + sig + " " + sig},
+		ScriptP2SH:        {SignatureScript: "0 " + sig + " " + redeem},
+		ScriptP2WPKH:      {Witness: sig + " " + pub},
+		ScriptP2WSH:       {Witness: sig + " " + redeem},
+		ScriptP2TR:        {Witness: strings.Repeat("ab", 64)},
+		ScriptNonStandard: {SignatureScript: "OP_DUP " + sig},
+	} {
+		in.DecodeScript(false)
+		if in.ScriptType != typ {
+			t.Errorf("DecodeScript return wrong input script type for %s, got: %s", typ, in.ScriptType)
+		}
+	}
+
+	// nested segwit spends p2sh output
+	nested := TxIn{SignatureScript: "0014" + strings.Repeat("11", 20), Witness: sig + " " + pub}
+	nested.DecodeScript(false)
+	if nested.ScriptType != ScriptP2SH {
+		t.Errorf("DecodeScript return wrong type for nested segwit, got: %s", nested.ScriptType)
+	}
+
+	coinbase := TxIn{SignatureScript: "04ffff001d0104"}
+	coinbase.DecodeScript(true)
+	if coinbase.ScriptType != ScriptCoinbase {
+		t.Errorf("DecodeScript return wrong type for coinbase, got: %s", coinbase.ScriptType)
+	}
+
+	// type of previous output set on import is kept
+	known := TxIn{SignatureScript: sig, ScriptType: ScriptP2SH}
+	known.DecodeScript(false)
+	if known.ScriptType != ScriptP2SH {
+		t.Errorf("DecodeScript should keep known script type, got: %s", known.ScriptType)
+	}
+
+	tr := Transaction{TxIns: []TxIn{known}}
+	txin, _ := tr.TxInJSONB()
+	ins, err := DecodeTxIns([]byte(txin))
+	if err != nil || ins[0].ScriptType != ScriptP2SH {
+		t.Errorf("TxInJSONB should store input script type, %+v, %v", ins, err)
+	}
+}
+
+func TestWitness(t *testing.T) {
+	t.Parallel()
+
+	in := TxIn{Witness: "3006020101020101 01 034f"}
+	stack, err := in.WitnessStack()
+	if err != nil || len(stack) != 3 || len(stack[0]) != 8 || stack[1][0] != 0x01 {
+		t.Errorf("WitnessStack return wrong items, %x, %v", stack, err)
+	}
+
+	in.DecodeScript(false)
+	if len(in.WitnessItems) != 3 || in.WitnessItems[2] != "034f" {
+		t.Errorf("DecodeScript return wrong witness items, %v", in.WitnessItems)
+	}
+
+	if _, err := (&TxIn{Witness: "zz"}).WitnessStack(); err == nil {
+		t.Errorf("WitnessStack should return error for wrong hex")
+	}
+
+	// p2wsh 2-of-2 multisig witness starts with empty item for CHECKMULTISIG bug
+	multisig, _ := hex.DecodeString("5221" + strings.Repeat("02", 33) + "21" + strings.Repeat("03", 33) + "52ae")
+	sig := append(append([]byte{0x30, 0x44}, make([]byte, 68)...), 0x01)
+	msg := wire.NewMsgTx(2)
+	msg.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil, wire.TxWitness{{}, sig, sig, multisig}))
+	msg.TxIn[0].PreviousOutPoint.Hash[0] = 0x01
+	msg.AddTxOut(wire.NewTxOut(9000, make([]byte, 22)))
+
+	p2wsh := FromWire(msg)
+	p2wsh.TxIns[0].DecodeScript(false)
+	if items := p2wsh.TxIns[0].WitnessItems; len(items) != 4 || items[0] != "" || items[1] != hex.EncodeToString(sig) {
+		t.Errorf("DecodeScript should keep empty witness item, %v", items)
+	}
+
+	if p2wsh.TxIns[0].ScriptType != ScriptP2WSH {
+		t.Errorf("DecodeScript return wrong p2wsh script type, %s", p2wsh.TxIns[0].ScriptType)
+	}
+
+	weight := p2wsh.Weight
+	p2wsh.Size, p2wsh.Weight = 0, 0
+	p2wsh.CalcFee()
+	if weight != msg.SerializeSizeStripped()*3+msg.SerializeSize() || p2wsh.Weight != weight || p2wsh.Size != msg.SerializeSize() {
+		t.Errorf("CalcFee return wrong p2wsh weight should %d, got: %d", weight, p2wsh.Weight)
+	}
+
+	rebuilt, err := p2wsh.ToWire()
+	if err != nil || rebuilt.WitnessHash() != msg.WitnessHash() || len(rebuilt.TxIn[0].Witness[0]) != 0 {
+		t.Errorf("ToWire should rebuild p2wsh witness, %v", err)
+	}
+
+	// BIP 350 test vector
+	out := TxOut{PkScript: "512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"}
+	addrs, err := out.GetAddresses()
+	if err != nil || len(addrs) != 1 || addrs[0] != "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0" {
+		t.Errorf("GetAddresses return wrong taproot address, %v, %v", addrs, err)
+	}
+
+	out = TxOut{PkScript: "0014751e76e8199196d454941c45d1b3a323f1433bd6"}
+	addrs, err = out.GetAddresses()
+	if err != nil || len(addrs) != 1 || addrs[0] != "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4" {
+		t.Errorf("GetAddresses return wrong p2wpkh address, %v, %v", addrs, err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), []byte(strings.Repeat("k", 32)))
+	keyHash := btcutil.Hash160(key.PubKey().SerializeCompressed())
+	p2pkhAddr, _ := btcutil.NewAddressPubKeyHash(keyHash, &chaincfg.MainNetParams)
+	p2wpkhAddr, _ := btcutil.NewAddressWitnessPubKeyHash(keyHash, &chaincfg.MainNetParams)
+	p2pkh, _ := txscript.PayToAddrScript(p2pkhAddr)
+	p2wpkh, _ := txscript.PayToAddrScript(p2wpkhAddr)
+
+	prev := chainhash.DoubleHashH([]byte("prev"))
+	msg := wire.NewMsgTx(2)
+	msg.LockTime = 500000
+	msg.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prev, 0), nil, nil))
+	msg.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prev, 1), nil, nil))
+	msg.AddTxOut(wire.NewTxOut(2500, p2pkh))
+
+	sigScript, err := txscript.SignatureScript(msg, 0, p2pkh, txscript.SigHashAll, key, true)
+	if err != nil {
+		t.Fatalf("cannot sign p2pkh input, %v", err)
+	}
+	msg.TxIn[0].SignatureScript = sigScript
+
+	witness, err := txscript.WitnessSignature(msg, txscript.NewTxSigHashes(msg), 1, 2000, p2wpkh, txscript.SigHashAll, key, true)
+	if err != nil {
+		t.Fatalf("cannot sign p2wpkh input, %v", err)
+	}
+	msg.TxIn[1].Witness = witness
+
+	tran := FromWire(msg)
+	if tran.Version != 2 || tran.LockTime != 500000 {
+		t.Errorf("FromWire return wrong version and lock time, %d, %d", tran.Version, tran.LockTime)
+	}
+	tran.BlockHeight = 500000
+
+	outs := []*TxOut{
+		{PkScript: hex.EncodeToString(p2pkh), Value: 1000},
+		{PkScript: hex.EncodeToString(p2wpkh), Value: 2000},
+	}
+
+	v := verify(&tran, outs)
+	if !v.Valid || len(v.Inputs) != 2 || v.Inputs[0].ScriptType != ScriptP2PKH || v.Inputs[1].Status != InputValid {
+		t.Errorf("verify should accept signed transaction, %+v", v)
+	}
+
+	// witness signature commits to previous output value
+	outs[1] = &TxOut{PkScript: hex.EncodeToString(p2wpkh), Value: 2001}
+	v = verify(&tran, outs)
+	if v.Valid || v.Inputs[0].Status != InputValid || v.Inputs[1].Status != InputInvalid {
+		t.Errorf("verify should reject input with wrong amount, %+v", v)
+	}
+
+	outs[0] = nil
+	v = verify(&tran, outs)
+	if failed := v.Failed(); len(failed) != 2 || failed[0].Status != InputUnknown {
+		t.Errorf("verify return wrong failed inputs, %+v", failed)
+	}
+
+	if v.Valid || v.Status != VerifyInvalid {
+		t.Errorf("verify should report unknown previous output as failure, %+v", v)
+	}
+
+	tran.TxOuts[0].Value++
+	v = verify(&tran, outs)
+	if v.Valid || v.Status != VerifyInvalid || v.Error != ErrRebuildMismatch.Error() || len(v.Inputs) != 0 {
+		t.Errorf("verify should report rebuild mismatch, %+v", v)
+	}
+
+	// older rows keep only disasm of signature script
+	tran.TxIns[0].ScriptHex = ""
+	v = verify(&tran, outs)
+	if v.Status != VerifyCannotRebuild || v.Error != ErrRebuildMismatch.Error() {
+		t.Errorf("verify should report transaction without raw scripts as cannot rebuild, %+v", v)
+	}
+
+	legacy := FromWire(msg)
+	legacy.noHeader = true
+	v = verify(&legacy, outs)
+	if v.Status != VerifyCannotRebuild || v.Error != ErrNoHeader.Error() || len(v.Failed()) != 0 {
+		t.Errorf("verify should report transaction without version as cannot rebuild, %+v", v)
+	}
+
+	// p2sh is not checked before its activation, block 170060 breaks its rules
+	if flags := verifyFlags(170060); flags&txscript.ScriptBip16 != 0 || flags&txscript.ScriptVerifyWitness != 0 {
+		t.Errorf("verifyFlags should not enable p2sh and segwit at height 170060, %d", flags)
+	}
+	if flags := verifyFlags(419328); flags&txscript.ScriptBip16 == 0 || flags&txscript.ScriptVerifyCheckSequenceVerify == 0 || flags&txscript.ScriptVerifyWitness != 0 {
+		t.Errorf("verifyFlags return wrong flags at csv activation, %d", flags)
+	}
+	if flags := verifyFlags(481824); flags&txscript.ScriptVerifyWitness == 0 {
+		t.Errorf("verifyFlags should enable segwit at its activation, %d", flags)
+	}
+
+	// single byte push of 0x10-0x16 is disassembled as small integer
+	pushes := wire.NewMsgTx(1)
+	pushes.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prev, 0), []byte{txscript.OP_DATA_1, 0x10, txscript.OP_16}, nil))
+	pushes.AddTxOut(wire.NewTxOut(1000, p2pkh))
+	pushTran := FromWire(pushes)
+	rebuilt, err := pushTran.ToWire()
+	if err != nil || rebuilt.TxHash() != pushes.TxHash() {
+		t.Errorf("ToWire should rebuild signature script from raw hex, %v", err)
+	}
+
+	script, _ := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_0).AddOp(txscript.OP_16).AddOp(txscript.OP_1NEGATE).
+		AddData([]byte{0x10, 0x00}).AddData(make([]byte, 80)).
+		AddOp(txscript.OP_CHECKMULTISIG).Script()
+	script = append(script, txscript.OP_DATA_1, 0x05)
+	if asm, err := asmScript(disasmString(script)); err != nil || !reflect.DeepEqual(asm, script) {
+		t.Errorf("asmScript should restore script %x, got: %x, %v", script, asm, err)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+
+	known := chainhash.DoubleHashH([]byte("known"))
+	unknown := chainhash.DoubleHashH([]byte("unknown"))
+	pkScript, _ := hex.DecodeString("76a914d4acec9b747f438152505781406b958548d1b62a88ac")
+
+	msg := wire.NewMsgTx(1)
+	msg.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&known, 0), []byte{txscript.OP_TRUE}, nil))
+	msg.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&known, 1), []byte{txscript.OP_TRUE}, nil))
+	msg.AddTxOut(wire.NewTxOut(250000000, pkScript))
+
+	load := func(hash string) (*Transaction, error) {
+		if hash != known.String() {
+			return nil, nil
+		}
+		return &Transaction{Hash: hash, TxOuts: []TxOut{
+			{PkScript: hex.EncodeToString(pkScript), Value: 100000000, AddressID: 7},
+			{PkScript: hex.EncodeToString(pkScript), Value: 200000000, AddressID: 7, SpentBy: &SpentBy{Hash: "other", Input: 3}},
+		}}, nil
+	}
+
+	d, err := decode(load, FromWire(msg), 40000)
+	if err != nil {
+		t.Fatalf("decode return error, %v", err)
+	}
+
+	tran := d.Transaction
+	if !d.Complete || tran.Fee != 50000000 || tran.FeeRate <= 0 || tran.TxIns[0].Address != "1LPXQf1foebcfLzZxcpK3sG2TJ9ke1uLyQ" || tran.TxIns[1].AddressID != 7 {
+		t.Errorf("decode return wrong inputs or fee, %+v", tran)
+	}
+
+	if d.Inputs[0].Status != InputUnspent || d.Inputs[1].Status != InputSpent || d.Inputs[1].SpentBy.Hash != "other" {
+		t.Errorf("decode return wrong input statuses, %+v", d.Inputs)
+	}
+
+	if d.ValueUSD != 100000 || d.FeeUSD != 20000 || tran.Price != 40000 {
+		t.Errorf("decode return wrong usd value, %f, %f", d.ValueUSD, d.FeeUSD)
+	}
+
+	msg.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&unknown, 0), []byte{txscript.OP_TRUE}, nil))
+	d, err = decode(load, FromWire(msg), 40000)
+	if err != nil || d.Complete || d.Inputs[2].Status != InputUnknown || d.Transaction.Fee != 0 || d.FeeUSD != 0 {
+		t.Errorf("decode should not calculate fee with unknown input, %+v, %v", d, err)
+	}
+
+	var buf bytes.Buffer
+	if err := msg.Serialize(&buf); err != nil {
+		t.Fatalf("cannot serialize transaction, %v", err)
+	}
+	if parsed, err := deserialize(buf.Bytes()); err != nil || parsed.TxHash() != msg.TxHash() {
+		t.Errorf("deserialize should parse raw transaction, %v", err)
+	}
+
+	trailing := hex.EncodeToString(buf.Bytes()) + "00"
+	for _, raw := range []string{"", "zz", "0100", trailing} {
+		if _, err := Decode(FakeStorage{}, raw); err != ErrBadRawTx {
+			t.Errorf("Decode should return ErrBadRawTx for %q, got: %v", raw, err)
+		}
+	}
+}
+
+func TestGetTransactionDetail(t *testing.T) {
+	t.Parallel()
+
+	f := FakeStorage{}
+	tr, err := GetTransactionDetail(f, "good_wallet")
+	if err != nil {
+		t.Fatalf("GetTransactionDetail return error: %v", err)
+	}
+
+	if tr.BlockHeight != 154734 || tr.Confirmations != 10 || tr.Price != 75.00 {
+		t.Errorf("GetTransactionDetail return wrong block data or price, %+v", tr)
+	}
+
+	if _, err := GetTransactionDetail(f, "bad_wallet"); err != ErrNoTran {
+		t.Errorf("GetTransactionDetail return wrong error, should: %v, got: %v", ErrNoTran, err)
+	}
+}
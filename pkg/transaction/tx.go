@@ -1,13 +1,15 @@
 package transaction
 
 import (
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"log"
 
-	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
 	"github.com/pkg/errors"
+	"github.com/webdeveloppro/cryptopiggy/pkg/network"
 )
 
 // ErrNonStandard Error for non standart output address
@@ -15,21 +17,44 @@ var ErrNonStandard = fmt.Errorf("Non standard output")
 
 // TxIn transaction incoming data
 type TxIn struct {
-	Amount          int64  `json:"amount"`
-	PrevOut         string `json:"prev_out"`
-	Size            int    `json:"size"`
-	SignatureScript string `json:"signature_script"`
-	Sequence        uint32 `json:"sequence"`
-	Witness         string `json:"witness"`
-	Address         string `json:"address"`
-	AddressID       uint   `json:"address_id"`
+	Amount          int64    `json:"amount"`
+	PrevOut         string   `json:"prev_out"`
+	PrevIndex       uint32   `json:"prev_index"`
+	Size            int      `json:"size"`
+	SignatureScript string   `json:"signature_script"`
+	ScriptHex       string   `json:"script_hex"` // raw signature script, empty for coinbase and older rows
+	Sequence        uint32   `json:"sequence"`
+	Witness         string   `json:"witness"`
+	Address         string   `json:"address"`
+	AddressID       uint     `json:"address_id"`
+	ScriptType      string   `json:"script_type"` // type of spent output or coinbase
+	Asm             string   `json:"asm"`
+	PubKey          string   `json:"pubkey"`
+	Signature       string   `json:"signature"`
+	WitnessItems    []string `json:"witness_items"` // witness stack items in hex
 }
 
 // TxOut transaction outcoming data
 type TxOut struct {
-	PkScript  string   `json:"pk_script"` // Hex version of PkScript
-	Value     int64    `json:"val"`
-	Addresses []string `json:"addresses"`
+	PkScript   string   `json:"pk_script"` // Hex version of PkScript
+	Value      int64    `json:"val"`
+	Addresses  []string `json:"addresses"`
+	AddressID  uint     `json:"address_id"`
+	SpentBy    *SpentBy `json:"spent_by"` // nil for unspent output
+	ScriptType string   `json:"script_type"`
+	Asm        string   `json:"asm"`
+	Data       *Data    `json:"data"` // OP_RETURN payload, nil for other outputs
+}
+
+// SpentBy points to transaction input which spends output
+type SpentBy struct {
+	Hash  string `json:"hash"`
+	Input uint32 `json:"input"`
+}
+
+// Outpoint return previous output in txid:vout format
+func (txIn *TxIn) Outpoint() string {
+	return fmt.Sprintf("%s:%d", txIn.PrevOut, txIn.PrevIndex)
 }
 
 // GetAddresses return addresses where money went
@@ -41,22 +66,33 @@ func (txOut *TxOut) GetAddresses() ([]string, error) {
 			return []string{}, errors.Wrap(err, "block: Cannot convert hex string to bytes")
 		}
 
-		typ, addresses, _, err := txscript.ExtractPkScriptAddrs(dst, &chaincfg.MainNetParams)
+		// OP_RETURN outputs are unspendable and have no addresses
+		if len(dst) > 0 && dst[0] == txscript.OP_RETURN {
+			txOut.Addresses = []string{}
+			return txOut.Addresses, nil
+		}
+
+		// btcd v0.22 cannot decode taproot outputs, they are encoded with bech32m here
+		if ScriptType(dst) == ScriptP2TR {
+			addr, err := taprootAddress(dst[2:], network.Params().Bech32HRPSegwit)
+			if err != nil {
+				return []string{}, err
+			}
+			txOut.Addresses = []string{addr}
+			return txOut.Addresses, nil
+		}
+
+		typ, addresses, _, err := txscript.ExtractPkScriptAddrs(dst, network.Params())
 		if err != nil {
 			return []string{}, errors.Wrap(err, fmt.Sprintf("Cannot extract pkScript %s", txOut.PkScript))
 		}
 
 		addrs := []string{}
 
-		// dirty hack for weired transactions
-		// like 9969603dca74d14d29d1d5f56b94c7872551607f8c2d6837ab9715c60721b50e
-		if typ == txscript.NonStandardTy {
-			// We still need to create some Addresses to work correctly, so we just create any hash
-			guessedAddr, err := txOut.guessNonstandardHash()
-			if err != nil {
-				return nil, err
-			}
-			addrs = append(addrs, guessedAddr)
+		// weired outputs like 9969603dca74d14d29d1d5f56b94c7872551607f8c2d6837ab9715c60721b50e
+		// still need some address to work correctly, so we key them by script
+		if typ == txscript.NonStandardTy || (typ == txscript.PubKeyTy && len(addresses) == 0) {
+			addrs = append(addrs, nonstandardAddress(dst))
 		} else {
 			for _, a := range addresses {
 				addrs = append(addrs, a.EncodeAddress())
@@ -67,17 +103,31 @@ func (txOut *TxOut) GetAddresses() ([]string, error) {
 	return txOut.Addresses, nil
 }
 
-// For Nonstandard output transactions we still need to get some hash
-// it is fix for being able to work with badly/unclear transactions
-// we will generate some hash from pkscript data
-func (txOut *TxOut) guessNonstandardHash() (string, error) {
+// NonstandardPrefix starts identifiers of outputs without standard address
+const NonstandardPrefix = "nonstandard-"
 
-	log.Printf("Nonstandard txout: %s", txOut.PkScript)
-
-	r := txOut.PkScript
-	if len(r) > 22 {
-		r = r[0:22]
+// nonstandardAddress return deterministic identifier for nonstandard output script.
+// P2PK with public key btcd cannot parse is converted to P2PKH address of the key,
+// any other script is identified by sha256 of the full script
+func nonstandardAddress(script []byte) string {
+	if pubKey := p2pkKey(script); pubKey != nil {
+		addr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(pubKey), network.Params())
+		if err == nil {
+			return addr.EncodeAddress()
+		}
 	}
 
-	return fmt.Sprintf("nonstandard-%s", r), nil
+	log.Printf("Nonstandard txout: %x", script)
+
+	hash := sha256.Sum256(script)
+	return NonstandardPrefix + hex.EncodeToString(hash[:])
+}
+
+// p2pkKey return public key push of <pubkey> OP_CHECKSIG script, nil for other scripts
+func p2pkKey(script []byte) []byte {
+	n := len(script)
+	if (n == 35 || n == 67) && int(script[0]) == n-2 && script[n-1] == txscript.OP_CHECKSIG {
+		return script[1 : n-1]
+	}
+	return nil
 }
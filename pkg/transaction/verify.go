@@ -0,0 +1,206 @@
+package transaction
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/webdeveloppro/cryptopiggy/pkg/network"
+)
+
+// ErrRebuildMismatch error for stored transaction which cannot be rebuilt to the same hash
+var ErrRebuildMismatch = fmt.Errorf("Rebuilt transaction hash mismatch")
+
+// ErrNoHeader error for transaction stored without version and lock time
+var ErrNoHeader = fmt.Errorf("Transaction version and lock time are not stored")
+
+// ErrPrevOutNotFound error for input which spends output missing in database
+var ErrPrevOutNotFound = fmt.Errorf("Previous output not found")
+
+// ErrTaprootNotSupported error for inputs btcd script engine cannot check
+var ErrTaprootNotSupported = fmt.Errorf("Taproot spends are not supported by script engine")
+
+// Input verification statuses
+const (
+	InputValid   = "valid"
+	InputInvalid = "invalid"
+	InputUnknown = "unknown" // previous output is not in database
+	InputSkipped = "skipped"
+)
+
+// Transaction verification statuses
+const (
+	VerifyValid         = "valid"
+	VerifyInvalid       = "invalid"
+	VerifyCannotRebuild = "cannot_rebuild" // stored fields are not enough to rebuild transaction, not a failure
+)
+
+// InputCheck is script verification result of one input
+type InputCheck struct {
+	Index      int    `json:"index"`
+	Outpoint   string `json:"outpoint"`
+	ScriptType string `json:"script_type"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Verification is script verification result of transaction, Valid is true when
+// every input passed. Error is set when transaction cannot be rebuilt from stored fields
+type Verification struct {
+	Hash        string       `json:"hash"`
+	BlockHeight int32        `json:"block_height"`
+	Valid       bool         `json:"valid"`
+	Status      string       `json:"status"`
+	Error       string       `json:"error,omitempty"`
+	Inputs      []InputCheck `json:"inputs"`
+}
+
+// Failed return inputs which did not pass verification, skipped inputs are not failed
+func (v *Verification) Failed() []InputCheck {
+	failed := make([]InputCheck, 0)
+	for _, in := range v.Inputs {
+		if in.Status == InputInvalid || in.Status == InputUnknown {
+			failed = append(failed, in)
+		}
+	}
+	return failed
+}
+
+// Verify rebuild transaction and run every input script against previous output
+// script and value from database. Script flags depend on t.BlockHeight
+func Verify(reader Storage, t *Transaction) (*Verification, error) {
+	prevOuts := make([]*TxOut, len(t.TxIns))
+	if !t.IsCoinbase() {
+		prevs := make(map[string]*Transaction)
+		for i, in := range t.TxIns {
+			prev, ok := prevs[in.PrevOut]
+			if !ok {
+				found, err := FindTransaction(reader, "hash", in.PrevOut)
+				if err != nil && err != ErrNoTran {
+					return nil, err
+				}
+				if err == nil {
+					prev = &found
+				}
+				prevs[in.PrevOut] = prev
+			}
+
+			if prev != nil && int(in.PrevIndex) < len(prev.TxOuts) {
+				prevOuts[i] = &prev.TxOuts[in.PrevIndex]
+			}
+		}
+	}
+	return verify(t, prevOuts), nil
+}
+
+// verify check transaction inputs against previous outputs, nil for unknown output
+func verify(t *Transaction, prevOuts []*TxOut) *Verification {
+	v := &Verification{
+		Hash:        t.Hash,
+		BlockHeight: t.BlockHeight,
+		Valid:       true,
+		Status:      VerifyValid,
+		Inputs:      make([]InputCheck, 0, len(t.TxIns)),
+	}
+
+	// coinbase input has no previous output to check
+	if t.IsCoinbase() {
+		return v
+	}
+
+	if t.noHeader {
+		v.Valid = false
+		v.Status = VerifyCannotRebuild
+		v.Error = ErrNoHeader.Error()
+		return v
+	}
+
+	msg, err := t.ToWire()
+	if err == nil && msg.TxHash().String() != t.Hash {
+		err = ErrRebuildMismatch
+	}
+	if err != nil {
+		v.Valid = false
+		v.Status = VerifyInvalid
+		// scripts assembled from disasm may differ from original ones
+		if !t.rawScripts() {
+			v.Status = VerifyCannotRebuild
+		}
+		v.Error = err.Error()
+		return v
+	}
+
+	hashes := txscript.NewTxSigHashes(msg)
+	flags := verifyFlags(t.BlockHeight)
+
+	for i, in := range t.TxIns {
+		check := InputCheck{Index: i, Outpoint: in.Outpoint(), Status: InputValid}
+		out := prevOuts[i]
+		if out == nil {
+			check.Status = InputUnknown
+			check.Error = ErrPrevOutNotFound.Error()
+			v.Valid = false
+			v.Status = VerifyInvalid
+			v.Inputs = append(v.Inputs, check)
+			continue
+		}
+
+		scriptType, err := execInput(msg, i, out, flags, hashes)
+		check.ScriptType = scriptType
+		if err == ErrTaprootNotSupported {
+			check.Status = InputSkipped
+			check.Error = err.Error()
+		} else if err != nil {
+			check.Status = InputInvalid
+			check.Error = err.Error()
+			v.Valid = false
+			v.Status = VerifyInvalid
+		}
+		v.Inputs = append(v.Inputs, check)
+	}
+	return v
+}
+
+// execInput run script engine for input i of msg, return script type of previous output
+func execInput(msg *wire.MsgTx, i int, out *TxOut, flags txscript.ScriptFlags, hashes *txscript.TxSigHashes) (string, error) {
+	pkScript, err := hex.DecodeString(out.PkScript)
+	if err != nil {
+		return "", err
+	}
+
+	scriptType := ScriptType(pkScript)
+	if scriptType == ScriptP2TR {
+		return scriptType, ErrTaprootNotSupported
+	}
+
+	vm, err := txscript.NewEngine(pkScript, msg, i, flags, nil, hashes, out.Value)
+	if err != nil {
+		return scriptType, err
+	}
+	return scriptType, vm.Execute()
+}
+
+// verifyFlags return consensus script flags active at block height
+func verifyFlags(height int32) txscript.ScriptFlags {
+	params := network.Params()
+	active := network.Activations()
+
+	var flags txscript.ScriptFlags
+	if height >= active.BIP16 {
+		flags |= txscript.ScriptBip16
+	}
+	if height >= params.BIP0066Height {
+		flags |= txscript.ScriptVerifyDERSignatures
+	}
+	if height >= params.BIP0065Height {
+		flags |= txscript.ScriptVerifyCheckLockTimeVerify
+	}
+	if height >= active.CSV {
+		flags |= txscript.ScriptVerifyCheckSequenceVerify
+	}
+	if height >= active.Segwit {
+		flags |= txscript.ScriptVerifyWitness
+	}
+	return flags
+}
@@ -0,0 +1,195 @@
+package transaction
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+)
+
+// FromWire convert btcd wire transaction to Transaction,
+// input amounts and addresses are not resolved, they depend on previous transactions
+func FromWire(tx *wire.MsgTx) Transaction {
+	t := Transaction{
+		Hash:       tx.TxHash().String(),
+		HasWitness: tx.HasWitness(),
+		Version:    tx.Version,
+		LockTime:   tx.LockTime,
+		Size:       tx.SerializeSize(),
+		TxIns:      make([]TxIn, 0, len(tx.TxIn)),
+		TxOuts:     make([]TxOut, 0, len(tx.TxOut)),
+		Addresses:  make([]uint, 0),
+	}
+
+	coinbase := len(tx.TxIn) == 1 && tx.TxIn[0].PreviousOutPoint.Hash.String() == zeroHash
+
+	for _, in := range tx.TxIn {
+		txIn := TxIn{
+			Size:     in.SerializeSize(),
+			Sequence: in.Sequence,
+			Witness:  witnessString(in.Witness),
+		}
+
+		if coinbase {
+			// coinbase script is arbitrary data, keep it as is
+			txIn.SignatureScript = hex.EncodeToString(in.SignatureScript)
+		} else {
+			txIn.PrevOut = in.PreviousOutPoint.Hash.String()
+			txIn.PrevIndex = in.PreviousOutPoint.Index
+			txIn.SignatureScript = disasmString(in.SignatureScript)
+			txIn.ScriptHex = hex.EncodeToString(in.SignatureScript)
+		}
+		t.TxIns = append(t.TxIns, txIn)
+	}
+
+	for _, out := range tx.TxOut {
+		t.TxOuts = append(t.TxOuts, TxOut{
+			PkScript: hex.EncodeToString(out.PkScript),
+			Value:    out.Value,
+		})
+	}
+
+	t.Weight = tx.SerializeSizeStripped()*(blockchain.WitnessScaleFactor-1) + tx.SerializeSize()
+	t.VSize = vsize(t.Weight)
+	t.WitnessDiscount = t.Size - t.VSize
+
+	t.DecodeScripts()
+	return t
+}
+
+// ToWire rebuild btcd wire transaction from stored fields. Older rows have no raw
+// signature scripts, they are assembled back from disasm so non minimal pushes are not restored
+func (t *Transaction) ToWire() (*wire.MsgTx, error) {
+	msg := wire.NewMsgTx(t.Version)
+	msg.LockTime = t.LockTime
+	coinbase := t.IsCoinbase()
+
+	for i, in := range t.TxIns {
+		var prev wire.OutPoint
+		var script []byte
+		var err error
+
+		if coinbase {
+			prev.Index = wire.MaxPrevOutIndex
+			script, err = hex.DecodeString(in.SignatureScript)
+		} else {
+			hash, herr := chainhash.NewHashFromStr(in.PrevOut)
+			if herr != nil {
+				return nil, errors.Wrapf(herr, "transaction: wrong previous output of input %d", i)
+			}
+			prev = wire.OutPoint{Hash: *hash, Index: in.PrevIndex}
+			if in.ScriptHex != "" || in.SignatureScript == "" {
+				script, err = hex.DecodeString(in.ScriptHex)
+			} else {
+				script, err = asmScript(in.SignatureScript)
+			}
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "transaction: cannot rebuild signature script of input %d", i)
+		}
+
+		witness, err := in.WitnessStack()
+		if err != nil {
+			return nil, errors.Wrapf(err, "transaction: cannot rebuild witness of input %d", i)
+		}
+
+		txIn := wire.NewTxIn(&prev, script, witness)
+		txIn.Sequence = in.Sequence
+		msg.AddTxIn(txIn)
+	}
+
+	for i, out := range t.TxOuts {
+		script, err := hex.DecodeString(out.PkScript)
+		if err != nil {
+			return nil, errors.Wrapf(err, "transaction: cannot decode pk_script of output %d", i)
+		}
+		msg.AddTxOut(wire.NewTxOut(out.Value, script))
+	}
+	return msg, nil
+}
+
+// rawScripts return false when signature script of some input was stored only as disasm
+func (t *Transaction) rawScripts() bool {
+	if t.IsCoinbase() {
+		return true
+	}
+	for _, in := range t.TxIns {
+		if in.ScriptHex == "" && in.SignatureScript != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// asmScript assemble script from one-line disasm produced by disasmString,
+// small integers are written as opcodes and hex items as data pushes
+func asmScript(disasm string) ([]byte, error) {
+	script := make([]byte, 0, len(disasm)/2)
+	for _, item := range strings.Fields(disasm) {
+		if op, ok := txscript.OpcodeByName[item]; ok {
+			script = append(script, op)
+			continue
+		}
+
+		// one-line disasm writes OP_0, OP_1NEGATE and OP_1-OP_16 as numbers,
+		// hex data always has even length, so only 10-16 are ambiguous
+		if n, err := strconv.Atoi(item); err == nil && n >= -1 && n <= 16 && item == strconv.Itoa(n) {
+			switch n {
+			case 0:
+				script = append(script, txscript.OP_0)
+			case -1:
+				script = append(script, txscript.OP_1NEGATE)
+			default:
+				script = append(script, byte(txscript.OP_1-1+n))
+			}
+			continue
+		}
+
+		data, err := hex.DecodeString(item)
+		if err != nil {
+			return nil, errors.Wrapf(err, "transaction: unknown script item %s", item)
+		}
+		script = pushData(script, data)
+	}
+	return script, nil
+}
+
+// pushData append data with the shortest push opcode for its length
+func pushData(script, data []byte) []byte {
+	n := len(data)
+	switch {
+	case n < txscript.OP_PUSHDATA1:
+		script = append(script, byte(n))
+	case n <= 0xff:
+		script = append(script, txscript.OP_PUSHDATA1, byte(n))
+	case n <= 0xffff:
+		script = append(script, txscript.OP_PUSHDATA2, byte(n), byte(n>>8))
+	default:
+		script = append(script, txscript.OP_PUSHDATA4, byte(n), byte(n>>8), byte(n>>16), byte(n>>24))
+	}
+	return append(script, data...)
+}
+
+// disasmString return script in the same one-line format btcd2sql stores,
+// script which cannot be parsed is stored as hex
+func disasmString(script []byte) string {
+	disasm, err := txscript.DisasmString(script)
+	if err != nil {
+		return hex.EncodeToString(script)
+	}
+	return disasm
+}
+
+// witnessString join witness stack items as hex separated by space
+func witnessString(witness wire.TxWitness) string {
+	items := make([]string, 0, len(witness))
+	for _, item := range witness {
+		items = append(items, hex.EncodeToString(item))
+	}
+	return strings.Join(items, " ")
+}
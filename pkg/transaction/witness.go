@@ -0,0 +1,118 @@
+package transaction
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcutil/bech32"
+	"github.com/pkg/errors"
+)
+
+// bech32mConst is checksum constant of bech32m encoding (BIP 350)
+const bech32mConst = 0x2bc830a3
+
+// bech32Charset is bech32 and bech32m alphabet
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// splitWitness return hex items of witness string. Empty items are kept,
+// P2WSH multisig witness starts with empty item for CHECKMULTISIG bug
+func splitWitness(witness string) []string {
+	if witness == "" {
+		return nil
+	}
+	return strings.Split(witness, " ")
+}
+
+// WitnessStack return witness items of input
+func (txIn *TxIn) WitnessStack() ([][]byte, error) {
+	items := splitWitness(txIn.Witness)
+	stack := make([][]byte, 0, len(items))
+	for i, item := range items {
+		data, err := hex.DecodeString(item)
+		if err != nil {
+			return nil, errors.Wrapf(err, "transaction: cannot decode witness item %d", i)
+		}
+		stack = append(stack, data)
+	}
+	return stack, nil
+}
+
+// vsize return virtual size of transaction weight
+func vsize(weight int) int {
+	return (weight + blockchain.WitnessScaleFactor - 1) / blockchain.WitnessScaleFactor
+}
+
+// taprootAddress return bech32m address of witness version 1 program
+func taprootAddress(program []byte, hrp string) (string, error) {
+	data, err := bech32.ConvertBits(program, 8, 5, true)
+	if err != nil {
+		return "", errors.Wrap(err, "transaction: cannot convert taproot program")
+	}
+	data = append([]byte{1}, data...)
+
+	values := make([]int, 0, len(hrp)*2+1+len(data)+6)
+	for _, c := range hrp {
+		values = append(values, int(c>>5))
+	}
+	values = append(values, 0)
+	for _, c := range hrp {
+		values = append(values, int(c&31))
+	}
+	for _, d := range data {
+		values = append(values, int(d))
+	}
+	values = append(values, 0, 0, 0, 0, 0, 0)
+	polymod := bech32Polymod(values) ^ bech32mConst
+
+	var b strings.Builder
+	b.WriteString(hrp)
+	b.WriteByte('1')
+	for _, d := range data {
+		b.WriteByte(bech32Charset[d])
+	}
+	for i := 0; i < 6; i++ {
+		b.WriteByte(bech32Charset[(polymod>>uint(5*(5-i)))&31])
+	}
+	return b.String(), nil
+}
+
+// IsTaprootAddress check that addr is bech32m witness version 1 address with 32 byte program for hrp
+func IsTaprootAddress(addr, hrp string) bool {
+	prefix := hrp + "1p"
+	if !strings.HasPrefix(addr, prefix) || len(addr) < len(prefix)+6 {
+		return false
+	}
+
+	values := make([]byte, 0, len(addr)-len(prefix)-6)
+	for _, c := range addr[len(prefix) : len(addr)-6] {
+		i := strings.IndexRune(bech32Charset, c)
+		if i < 0 {
+			return false
+		}
+		values = append(values, byte(i))
+	}
+
+	program, err := bech32.ConvertBits(values, 5, 8, false)
+	if err != nil || len(program) != 32 {
+		return false
+	}
+	encoded, err := taprootAddress(program, hrp)
+	return err == nil && encoded == addr
+}
+
+// bech32Polymod is checksum function shared by bech32 and bech32m
+func bech32Polymod(values []int) int {
+	gen := []int{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := 1
+	for _, v := range values {
+		b := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ v
+		for i := 0; i < 5; i++ {
+			if (b>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx"
+	"github.com/webdeveloppro/cryptopiggy/pkg/block"
+)
+
+// checkRewards compare coinbase outputs with subsidy plus fees for blocks in
+// height range, store result and report blocks with unclaimed or excess reward
+// usage: ./bitcoin2sql rewards [from height] [to height]
+func checkRewards(pool *pgx.ConnPool, args []string) error {
+	storage := block.NewStorage(pool)
+
+	from, to, err := heightArgs(&storage, args)
+	if err != nil {
+		return fmt.Errorf("rewards: %v", err)
+	}
+
+	var unclaimed int64
+	invalid := 0
+	for height := from; height <= to; height++ {
+		b, err := storage.GetByHeight(int32(height))
+		if err == pgx.ErrNoRows {
+			log.Printf("rewards: block %d not found", height)
+			continue
+		} else if err != nil {
+			return fmt.Errorf("rewards: cannot get block %d, %v", height, err)
+		}
+
+		b.Reward = nil
+		r, err := b.GetReward()
+		if err != nil {
+			log.Printf("rewards: block %d, %v", height, err)
+			continue
+		}
+
+		if err := storage.SaveReward(b.ID, r); err != nil {
+			return fmt.Errorf("rewards: block %d, %v", height, err)
+		}
+
+		if !r.Valid() {
+			log.Printf("rewards: block %d claimed %d more than allowed, subsidy: %d, fees: %d, claimed: %d",
+				height, -r.Unclaimed, r.Subsidy, r.Fees, r.Claimed)
+			invalid++
+		} else if r.Unclaimed > 0 {
+			log.Printf("rewards: block %d left %d unclaimed", height, r.Unclaimed)
+			unclaimed += r.Unclaimed
+		}
+	}
+
+	log.Printf("rewards: checked blocks %d-%d, unclaimed: %d, invalid: %d", from, to, unclaimed, invalid)
+	if invalid > 0 {
+		return fmt.Errorf("rewards: %d blocks claimed more than allowed", invalid)
+	}
+	return nil
+}
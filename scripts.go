@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx"
+	"github.com/webdeveloppro/cryptopiggy/pkg/block"
+	"github.com/webdeveloppro/cryptopiggy/pkg/transaction"
+)
+
+// verifyScripts run script engine over inputs of transactions in height range
+// and log inputs which fail verification
+// usage: ./bitcoin2sql scripts [from height] [to height]
+func verifyScripts(pool *pgx.ConnPool, args []string) error {
+	storage := block.NewStorage(pool)
+	tranStorage := transaction.NewStorage(pool)
+
+	from, to, err := heightArgs(&storage, args)
+	if err != nil {
+		return fmt.Errorf("scripts: %v", err)
+	}
+
+	checked, failed, skipped := 0, 0, 0
+	for height := from; height <= to; height++ {
+		b, err := storage.GetByHeight(int32(height))
+		if err == pgx.ErrNoRows {
+			log.Printf("scripts: block %d not found", height)
+			continue
+		} else if err != nil {
+			return fmt.Errorf("scripts: cannot get block %d, %v", height, err)
+		}
+
+		trans, err := b.GetTransactions()
+		if err != nil {
+			return fmt.Errorf("scripts: cannot get transactions for block %d, %v", height, err)
+		}
+
+		for i := range trans {
+			t := &trans[i]
+			t.BlockHeight = b.Height
+
+			v, err := transaction.Verify(tranStorage, t)
+			if err != nil {
+				return fmt.Errorf("scripts: cannot verify %s, %v", t.Hash, err)
+			}
+			checked++
+
+			switch v.Status {
+			case transaction.VerifyValid:
+				continue
+			case transaction.VerifyCannotRebuild:
+				// rows imported by older versions, reimport blocks to verify them
+				skipped++
+				continue
+			}
+			failed++
+
+			if v.Error != "" {
+				log.Printf("scripts: block %d transaction %s, %s", height, t.Hash, v.Error)
+			}
+			for _, in := range v.Failed() {
+				log.Printf("scripts: block %d transaction %s input %d (%s) %s, %s", height, t.Hash, in.Index, in.Outpoint, in.Status, in.Error)
+			}
+		}
+	}
+
+	log.Printf("scripts: checked %d transactions in blocks %d-%d, failed: %d, cannot rebuild: %d", checked, from, to, failed, skipped)
+	if failed > 0 {
+		return fmt.Errorf("scripts: %d transactions failed verification", failed)
+	}
+	return nil
+}
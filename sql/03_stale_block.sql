@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS stale_block;
+CREATE TABLE stale_block (
+  id serial PRIMARY KEY,
+  block_id int not null default 0,          /* id block had in the block table */
+  bits int not null default 0,
+  height int not null default 0,
+  nonce	 bigint not null default 0,
+  version int not null default 0,
+  hash_prev_block varchar(64) not null default '',
+  hash_merkle_root varchar(64) not null default '',
+  created_at timestamp not null default now(),
+  hash varchar(64) not null default '',
+  stale_at timestamp not null default now()
+);
+
+create index stale_block_height on stale_block(height);
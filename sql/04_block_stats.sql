@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS block_stats;
+CREATE TABLE block_stats (
+  block_id integer PRIMARY KEY references block(id) ON DELETE CASCADE,
+  tx_count int not null default 0,
+  total_in bigint not null default 0,
+  total_out bigint not null default 0,
+  total_fee bigint not null default 0,           /* inputs minus outputs, coinbase excluded */
+  largest_tx varchar(64) not null default '',
+  largest_tx_value bigint not null default 0,
+  price decimal(10, 2) not null default 0,       /* btc_price on the moment block was created */
+  total_in_usd decimal(20, 2) not null default 0,
+  total_out_usd decimal(20, 2) not null default 0,
+  total_fee_usd decimal(20, 2) not null default 0,
+  largest_tx_usd decimal(20, 2) not null default 0
+);
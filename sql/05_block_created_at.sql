@@ -0,0 +1,2 @@
+/* block lookup by timestamp and date filters */
+create index block_created_at on block(created_at);
@@ -0,0 +1,35 @@
+DROP TABLE IF EXISTS pool;
+CREATE TABLE pool (
+  id serial PRIMARY KEY,
+  name varchar(64) not null default '' UNIQUE,
+  url varchar(256) not null default '',
+  tags jsonb NOT NULL DEFAULT '[]'::jsonb,        /* case insensitive coinbase text patterns */
+  addresses jsonb NOT NULL DEFAULT '[]'::jsonb    /* coinbase payout addresses */
+);
+
+DROP TABLE IF EXISTS block_miner;
+CREATE TABLE block_miner (
+  block_id integer PRIMARY KEY references block(id) ON DELETE CASCADE,
+  pool varchar(64) not null default 'unknown',
+  height int default null,                        /* BIP34 height from coinbase */
+  tags jsonb NOT NULL DEFAULT '[]'::jsonb,
+  addresses jsonb NOT NULL DEFAULT '[]'::jsonb
+);
+
+create index block_miner_pool on block_miner(pool);
+
+INSERT INTO pool (name, url, tags, addresses) VALUES
+  ('Slush', 'https://slushpool.com', '["/slush/"]', '[]'),
+  ('Eligius', 'http://eligius.st', '["Eligius"]', '[]'),
+  ('BTC Guild', 'https://www.btcguild.com', '["Mined by BTC Guild", "BTC Guild"]', '[]'),
+  ('GHash.IO', 'https://ghash.io', '["ghash.io"]', '[]'),
+  ('50BTC', 'https://50btc.com', '["50BTC"]', '[]'),
+  ('ASICMiner', 'http://www.asicminer.co', '["Mined By ASICMiner"]', '[]'),
+  ('P2Pool', 'http://p2pool.org', '["/P2Pool/"]', '[]'),
+  ('AntPool', 'https://www.antpool.com', '["Mined by AntPool", "/AntPool/"]', '[]'),
+  ('F2Pool', 'https://www.f2pool.com', '["/F2Pool/"]', '[]'),
+  ('BTC.com', 'https://pool.btc.com', '["/BTC.COM/"]', '[]'),
+  ('ViaBTC', 'https://viabtc.com', '["/ViaBTC/"]', '[]'),
+  ('BTC.TOP', 'http://www.btc.top', '["/BTC.TOP/"]', '[]'),
+  ('Poolin', 'https://www.poolin.com', '["/poolin.com"]', '[]'),
+  ('Foundry USA', 'https://foundrydigital.com', '["/Foundry USA Pool"]', '[]');
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS block_reward;
+CREATE TABLE block_reward (
+  block_id integer PRIMARY KEY references block(id) ON DELETE CASCADE,
+  subsidy bigint not null default 0,
+  fees bigint not null default 0,
+  claimed bigint not null default 0,        /* sum of coinbase outputs */
+  unclaimed bigint not null default 0       /* subsidy + fees - claimed, lost coins if positive */
+);
+
+/* blocks which claimed less or more than allowed */
+create index block_reward_unclaimed on block_reward(unclaimed) WHERE unclaimed != 0;
@@ -0,0 +1,4 @@
+ALTER TABLE transaction ADD COLUMN fee bigint not null default 0;     /* inputs minus outputs, 0 for coinbase */
+ALTER TABLE transaction ADD COLUMN size int not null default 0;       /* serialized size in bytes */
+ALTER TABLE transaction ADD COLUMN vsize int not null default 0;      /* virtual size for fee rate */
+ALTER TABLE transaction ADD COLUMN fee_rate double precision not null default 0;  /* satoshi per virtual byte */
@@ -0,0 +1,13 @@
+/* Outpoint index: which transaction input spends every output */
+DROP TABLE IF EXISTS spent_output;
+CREATE TABLE spent_output(
+    hash varchar(64) not null,       /* transaction hash of spent output */
+    vout int not null,               /* spent output index */
+    spent_by varchar(64) not null,   /* transaction hash of spending input */
+    vin int not null,                /* spending input index */
+    PRIMARY KEY (hash, vout)
+);
+
+CREATE INDEX spent_output_spent_by ON spent_output (spent_by);
+
+/* Inputs imported before prev_index was stored have no output index, reimport blocks to fill this table */
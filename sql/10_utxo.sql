@@ -0,0 +1,19 @@
+/* Unspent transaction outputs, maintained on block insert and orphan */
+CREATE TABLE utxo(
+    hash varchar(64) not null,
+    vout int not null,
+    address_id int not null default 0,   /* 0 for outputs without address */
+    value bigint not null,
+    height int not null,                 /* height of block which created output */
+    price decimal(10, 2) not null default 0,  /* btc_price on the moment output was created */
+    PRIMARY KEY (hash, vout)
+);
+
+CREATE INDEX utxo_address_id ON utxo (address_id);
+CREATE INDEX utxo_height ON utxo (height);
+
+/* utxo set is not built from already imported transactions: spent_output (sql/09) is
+   filled only on block insert, so old outputs cannot be told spent from unspent.
+   utxo and spent_output are filled only by block.Insert, blocks converted by btcd2sql do not
+   have them. Until blocks are imported through block.Insert, /address/{hash}/utxo answers
+   "utxo set not built" for addresses with positive ballance */
@@ -0,0 +1,17 @@
+/* OP_RETURN payloads for embedded data search */
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE TABLE tx_data(
+    transaction_id integer not null references transaction(id) ON DELETE CASCADE,
+    vout int not null,
+    hex text not null default '',
+    text text not null default '',        /* printable parts of payload */
+    protocol varchar(32) not null default '',
+    PRIMARY KEY (transaction_id, vout)
+);
+
+CREATE INDEX tx_data_hex ON tx_data USING gin (hex gin_trgm_ops);
+CREATE INDEX tx_data_text ON tx_data USING gin (lower(text) gin_trgm_ops);
+CREATE INDEX tx_data_protocol ON tx_data (protocol);
+
+/* Outputs imported before this table are indexed by reimporting blocks */
@@ -0,0 +1,4 @@
+/* Nonstandard outputs are keyed by nonstandard-<sha256 of script>, 76 chars */
+ALTER TABLE address ALTER COLUMN hash TYPE varchar(80);
+
+/* Existing nonstandard-<script prefix> addresses are migrated by ./bitcoin2sql nonstandard */
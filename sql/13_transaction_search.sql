@@ -0,0 +1,11 @@
+ALTER TABLE transaction ADD COLUMN value bigint not null default 0;   /* sum of outputs */
+
+UPDATE transaction SET value = coalesce(
+    (SELECT sum((o->>'val')::bigint) FROM jsonb_array_elements(txout) as o), 0
+);
+
+CREATE INDEX transaction_block_id ON transaction (block_id);
+CREATE INDEX transaction_value ON transaction (value);
+CREATE INDEX transaction_fee ON transaction (fee);
+CREATE INDEX transaction_fee_rate ON transaction (fee_rate);
+CREATE INDEX transaction_addresses ON transaction USING gin (addresses jsonb_path_ops);
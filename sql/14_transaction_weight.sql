@@ -0,0 +1,5 @@
+ALTER TABLE transaction ADD COLUMN weight int not null default 0;            /* base size * 3 + total size */
+ALTER TABLE transaction ADD COLUMN witness_discount int not null default 0;  /* size minus vsize */
+
+/* Transactions without witness weight 4 units per byte, witness transactions are recalculated on read */
+UPDATE transaction SET weight = size * 4 WHERE size > 0 AND NOT has_witness;
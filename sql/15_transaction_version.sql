@@ -0,0 +1,5 @@
+ALTER TABLE transaction ADD COLUMN version int;
+ALTER TABLE transaction ADD COLUMN lock_time bigint;
+
+/* Transactions imported before this migration have NULL version and lock time,
+   script verification reports them as cannot rebuild until blocks are reimported */
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx"
+	"github.com/webdeveloppro/cryptopiggy/pkg/block"
+)
+
+// calcStats calculate statistics for blocks in height range and store them,
+// existing statistics are replaced, blocks imported by btcd2sql have none
+// usage: ./bitcoin2sql stats [from height] [to height]
+func calcStats(pool *pgx.ConnPool, args []string) error {
+	storage := block.NewStorage(pool)
+
+	from, to, err := heightArgs(&storage, args)
+	if err != nil {
+		return fmt.Errorf("stats: %v", err)
+	}
+
+	for height := from; height <= to; height++ {
+		b, err := storage.GetByHeight(int32(height))
+		if err == pgx.ErrNoRows {
+			log.Printf("stats: block %d not found", height)
+			continue
+		} else if err != nil {
+			return fmt.Errorf("stats: cannot get block %d, %v", height, err)
+		}
+
+		b.Stats = nil
+		s, err := b.GetStats()
+		if err != nil {
+			return fmt.Errorf("stats: block %d, %v", height, err)
+		}
+
+		if err := storage.SaveStats(b.ID, s); err != nil {
+			return fmt.Errorf("stats: block %d, %v", height, err)
+		}
+	}
+
+	log.Printf("stats: calculated blocks %d-%d", from, to)
+	return nil
+}
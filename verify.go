@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx"
+	"github.com/webdeveloppro/cryptopiggy/pkg/block"
+)
+
+// verifyBlocks check proof of work and merkle root for blocks in height range
+// usage: ./bitcoin2sql verify [from height] [to height]
+func verifyBlocks(pool *pgx.ConnPool, args []string) error {
+	storage := block.NewStorage(pool)
+
+	from, to, err := heightArgs(&storage, args)
+	if err != nil {
+		return fmt.Errorf("verify: %v", err)
+	}
+
+	failed := 0
+	for height := from; height <= to; height++ {
+		b, err := storage.GetByHeight(int32(height))
+		if err == pgx.ErrNoRows {
+			log.Printf("verify: block %d not found", height)
+			failed++
+			continue
+		} else if err != nil {
+			return fmt.Errorf("verify: cannot get block %d, %v", height, err)
+		}
+
+		if _, err := b.GetTransactions(); err != nil {
+			return fmt.Errorf("verify: cannot get transactions for block %d, %v", height, err)
+		}
+
+		if err := block.Verify(b); err != nil {
+			log.Printf("verify: %v", err)
+			failed++
+		}
+	}
+
+	log.Printf("verify: checked blocks %d-%d, failed: %d", from, to, failed)
+	if failed > 0 {
+		return fmt.Errorf("verify: %d blocks failed verification", failed)
+	}
+	return nil
+}